@@ -19,9 +19,27 @@ function run_revoke() {
     PAYLOAD=$(echo "$*" | base64 -d)
     PAYLOAD_VALUE=$(echo "$PAYLOAD" | jq -r .)
 
-    USER1=$(echo "$PAYLOAD_VALUE" | jq -r .ids[0])
-    USER1=$(echo "$USER1" | sed -E 's/^tmp\.[0-9a-zA-Z]+_//')
-    curl -f -s -ku ${SPLUNK_AUSR}:${SPLUNK_APWD} -X DELETE "${SPLUNK_URL}/services/authentication/users/${USER1}?output_mode=json" | jq . >> /dev/null
-    echo "{ \"revoked\": [\"${USER1}\"], \"message\": \"User [$USER1] was successfully deleted\" }"
-}
+    # every id is revoked, and only the ones that were deleted are reported,
+    # so that the gateway retries only the failed ones. A user that doesn't
+    # exist (404) was already deleted, so it counts as revoked.
+    REVOKED="[]"
+    FAILED=""
+    for ID in $(echo "$PAYLOAD_VALUE" | jq -r '.ids[]'); do
+        USER1=$(echo "$ID" | sed -E 's/^tmp\.[0-9a-zA-Z]+_//')
+        STATUS=$(curl -s -o /dev/null -w '%{http_code}' -ku ${SPLUNK_AUSR}:${SPLUNK_APWD} -X DELETE "${SPLUNK_URL}/services/authentication/users/${USER1}?output_mode=json")
+        case "$STATUS" in
+        2??|404)
+            REVOKED=$(echo "$REVOKED" | jq -c --arg id "$ID" '. + [$id]')
+            ;;
+        *)
+            FAILED="${FAILED} ${ID}"
+            ;;
+        esac
+    done
 
+    if [ -n "$FAILED" ]; then
+        jq -n -c --argjson revoked "$REVOKED" --arg failed "${FAILED# }" '{revoked: $revoked, message: ("failed to revoke: " + $failed)}'
+    else
+        jq -n -c --argjson revoked "$REVOKED" '{revoked: $revoked}'
+    fi
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"strings"
 
 	"github.com/akeylesslabs/custom-producer/go/echoserver/pkg/producer"
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 )
@@ -15,8 +18,9 @@ import (
 func handleRequest(ctx context.Context, r events.APIGatewayV2HTTPRequest) (interface{}, error) {
 	// NOTE: request body may contain sensitive data, for example, secret
 	// payload specified when the producer was created, or user input that may
-	// also include passwords/tokens. Avoid logging the entire request body.
-	log.Println("new request:", r.RawPath, r.Body)
+	// also include passwords/tokens. The logger masks known sensitive fields,
+	// but avoid logging the entire request body in production anyway.
+	slog.Info("new request", "path", r.RawPath, "headers", r.Headers, "body", json.RawMessage(r.Body))
 
 	p := &producer.Producer{}
 
@@ -32,31 +36,32 @@ func handleRequest(ctx context.Context, r events.APIGatewayV2HTTPRequest) (inter
 
 	switch strings.TrimSuffix(r.RawPath, "/") {
 	case "/sync/create":
-		var cr *producer.CreateRequest
+		var cr protocol.CreateRequest
 		if err := json.Unmarshal([]byte(r.Body), &cr); err != nil {
 			return nil, fmt.Errorf("invalid request: %w", err)
 		}
 
-		return p.Create(cr)
+		return p.Create(ctx, &cr)
 	case "/sync/revoke":
-		var rr *producer.RevokeRequest
+		var rr protocol.RevokeRequest
 		if err := json.Unmarshal([]byte(r.Body), &rr); err != nil {
 			return nil, fmt.Errorf("invalid request: %w", err)
 		}
 
-		return p.Revoke(rr)
+		return p.Revoke(ctx, &rr)
 	case "/sync/rotate":
-		var rr *producer.RotateRequest
+		var rr protocol.RotateRequest
 		if err := json.Unmarshal([]byte(r.Body), &rr); err != nil {
 			return nil, fmt.Errorf("invalid request: %w", err)
 		}
 
-		return p.Rotate(rr)
+		return p.Rotate(ctx, &rr)
 	default:
 		return nil, fmt.Errorf("invalid request path '%s'", r.RawPath)
 	}
 }
 
 func main() {
+	slog.SetDefault(logging.New(os.Stderr, logging.ParseLevel(os.Getenv("LOG_LEVEL"))))
 	lambda.Start(handleRequest)
 }
@@ -4,13 +4,12 @@
 package producer
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"time"
-)
 
-var blankStringBytes = []byte(`""`)
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
 
 // Producer is a simple custom producer implementation that can be deployed
 // anywhere and used for tests. It doesn't include authentication!
@@ -18,87 +17,29 @@ type Producer struct{}
 
 // Create sends back the incoming request as a "Response", and uses current
 // timestamp (nano-second resolution) as an ID.
-func (p *Producer) Create(r *CreateRequest) (*CreateResponse, error) {
-	return &CreateResponse{
+func (p *Producer) Create(_ context.Context, r *protocol.CreateRequest) (*protocol.CreateResponse, error) {
+	return &protocol.CreateResponse{
 		ID:       fmt.Sprintf("%d", time.Now().UnixNano()),
 		Response: r,
 	}, nil
 }
 
 // Revoke sends back all the received IDs.
-func (p *Producer) Revoke(r *RevokeRequest) (*RevokeResponse, error) {
-	return &RevokeResponse{
+func (p *Producer) Revoke(_ context.Context, r *protocol.RevokeRequest) (*protocol.RevokeResponse, error) {
+	return &protocol.RevokeResponse{
 		Revoked: r.IDs,
 	}, nil
 }
 
-// Rotate generates and sends back a new payload.
-func (p *Producer) Rotate(r *RotateRequest) (*RotateResponse, error) {
-	return &RotateResponse{
-		Payload: fmt.Sprintf("%d", time.Now().UnixNano()),
-	}, nil
-}
-
-// CreateRequest represents requests to /sync/create endpoint to create
-// temporary credentials.
-type CreateRequest struct {
-	Payload    string     `json:"payload"`
-	ClientInfo ClientInfo `json:"client_info"`
-	Input      Input      `json:"input,omitempty"`
-}
-
-// Input is the input that this producer accepts. It can be any JSON object.
-type Input map[string]interface{}
-
-// UnmarshalJSON implements json.Unmarshaler.
-func (i *Input) UnmarshalJSON(data []byte) error {
-	if bytes.Equal(data, blankStringBytes) {
-		return nil
-	}
-
-	type inputAlias Input
-	var ii *inputAlias
-
-	if err := json.Unmarshal(data, &ii); err != nil {
-		return fmt.Errorf("cannot unmarshal '%s': %w", string(data), err)
-	}
-
-	*i = Input(*ii)
+// RevokeID revokes a single ID. It allows the webhook to revoke IDs
+// concurrently, and to report only the ones that were revoked.
+func (p *Producer) RevokeID(_ context.Context, _ string, _ string) error {
 	return nil
 }
 
-// ClientInfo wraps original user information, such as Access ID or sub-claims.
-type ClientInfo struct {
-	AccessID  string              `json:"access_id"`
-	SubClaims map[string][]string `json:"sub_claims"`
-}
-
-// CreateResponse is returned by "create" operation.
-type CreateResponse struct {
-	ID       string      `json:"id"`
-	Response interface{} `json:"response"`
-}
-
-// RevokeRequest represents revocation requests made by Akeyless Custom
-// Producer.
-type RevokeRequest struct {
-	Payload string   `json:"payload"`
-	IDs     []string `json:"ids"`
-}
-
-// RevokeResponse is returned by revoke operation.
-type RevokeResponse struct {
-	Revoked []string `json:"revoked"`
-	Message string   `json:"message,omitempty"`
-}
-
-// RotateRequest represents admin credentials rotation requests made by
-// Akeyless Custom Producer.
-type RotateRequest struct {
-	Payload string `json:"payload"`
-}
-
-// RotateResponse is returned by rotate operation.
-type RotateResponse struct {
-	Payload string `json:"payload"`
+// Rotate generates and sends back a new payload.
+func (p *Producer) Rotate(_ context.Context, r *protocol.RotateRequest) (*protocol.RotateResponse, error) {
+	return &protocol.RotateResponse{
+		Payload: fmt.Sprintf("%d", time.Now().UnixNano()),
+	}, nil
 }
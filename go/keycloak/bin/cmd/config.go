@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/config"
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/server"
+)
+
+// Config configures Keycloak producer and its server.
+type Config struct {
+	Akeyless Akeyless      `yaml:"akeyless" toml:"akeyless" json:"akeyless"`
+	Keycloak Keycloak      `yaml:"keycloak" toml:"keycloak" json:"keycloak"`
+	Server   server.Config `yaml:"server" toml:"server" json:"server"`
+	Log      Log           `yaml:"log" toml:"log" json:"log"`
+	Tracing  Tracing       `yaml:"tracing" toml:"tracing" json:"tracing"`
+
+	// PayloadKeys are base64 encoded AES-256 keys that decrypt producer
+	// payloads. If set, plain payloads are rejected.
+	PayloadKeys []string `yaml:"payload_keys" toml:"payload_keys" json:"payload_keys" env:"PAYLOAD_KEYS"`
+
+	// ReplayWindow enables rejection of replayed credentials if set.
+	ReplayWindow config.Duration `yaml:"replay_window" toml:"replay_window" json:"replay_window" env:"REPLAY_WINDOW"`
+}
+
+// Akeyless restricts which producers may call this server.
+type Akeyless struct {
+	AccessID string `yaml:"access_id" toml:"access_id" json:"access_id" env:"AKEYLESS_ACCESS_ID"`
+	ItemName string `yaml:"item_name" toml:"item_name" json:"item_name" env:"AKEYLESS_ITEM_NAME"`
+}
+
+// Keycloak configures the producer itself.
+type Keycloak struct {
+	// URL is the Keycloak URL of payloads that don't include one.
+	URL             string `yaml:"url" toml:"url" json:"url" env:"KEYCLOAK_URL"`
+	PasswordLength  int    `yaml:"password_length" toml:"password_length" json:"password_length" env:"KEYCLOAK_PASSWORD_LENGTH"`
+	PasswordSymbols bool   `yaml:"password_symbols" toml:"password_symbols" json:"password_symbols" env:"KEYCLOAK_PASSWORD_SYMBOLS"`
+}
+
+// Log configures logging.
+type Log struct {
+	Level string `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL"`
+}
+
+// Tracing configures OpenTelemetry tracing.
+type Tracing struct {
+	Exporter string `yaml:"exporter" toml:"exporter" json:"exporter" env:"OTEL_TRACES_EXPORTER"`
+}
+
+// Validate implements config.Validator. Every problem is reported at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Akeyless.AccessID == "" {
+		errs = append(errs, errors.New("akeyless.access_id is required"))
+	}
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, errors.New("server.tls_cert_file and server.tls_key_file must be set together"))
+	}
+
+	if c.Server.ClientCAFile != "" && c.Server.TLSCertFile == "" {
+		errs = append(errs, errors.New("server.client_ca_file requires TLS"))
+	}
+
+	for i, key := range c.PayloadKeys {
+		if _, err := payload.ParseKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("payload_keys[%d]: %w", i, err))
+		}
+	}
+
+	if c.Keycloak.URL != "" && !strings.HasPrefix(c.Keycloak.URL, "https://") {
+		errs = append(errs, errors.New("keycloak.url must be an https URL"))
+	}
+
+	if c.Keycloak.PasswordLength < 0 {
+		errs = append(errs, errors.New("keycloak.password_length must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+// Command cmd serves Keycloak producer, which rotates Keycloak passwords.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/keycloak/pkg/producer"
+	"github.com/akeylesslabs/custom-producer/go/pkg/config"
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/replay"
+	"github.com/akeylesslabs/custom-producer/go/pkg/server"
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"github.com/akeylesslabs/custom-producer/go/pkg/webhook"
+)
+
+func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of a YAML, TOML or JSON config file")
+	flag.Parse()
+
+	var cfg Config
+	if err := config.Load(*configPath, &cfg); err != nil {
+		fatal(err)
+	}
+
+	slog.SetDefault(logging.New(os.Stderr, logging.ParseLevel(cfg.Log.Level)))
+
+	shutdownTracing, err := tracing.Setup(
+		context.Background(),
+		tracing.WithExporter(cfg.Tracing.Exporter),
+		tracing.WithServiceName("keycloak-producer"),
+	)
+	if err != nil {
+		fatal(err)
+	}
+
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
+
+	popts := []producer.Option{
+		producer.WithDefaultURL(cfg.Keycloak.URL),
+		producer.WithPasswordSymbols(cfg.Keycloak.PasswordSymbols),
+	}
+
+	if n := cfg.Keycloak.PasswordLength; n > 0 {
+		popts = append(popts, producer.WithPasswordLength(n))
+	}
+
+	p, err := producer.New(popts...)
+	if err != nil {
+		fatal(err)
+	}
+
+	opts := []webhook.Option{
+		webhook.WithAllowedAccessID(cfg.Akeyless.AccessID),
+		webhook.WithAllowedItemName(cfg.Akeyless.ItemName),
+	}
+
+	if len(cfg.PayloadKeys) > 0 {
+		keys := make([][]byte, 0, len(cfg.PayloadKeys))
+
+		// keys were validated when the config was loaded
+		for _, k := range cfg.PayloadKeys {
+			key, _ := payload.ParseKey(k)
+			keys = append(keys, key)
+		}
+
+		opts = append(opts, webhook.WithPayloadKeys(keys...))
+	}
+
+	if w := time.Duration(cfg.ReplayWindow); w > 0 {
+		opts = append(opts, webhook.WithReplayGuard(replay.NewMemory(), w))
+	}
+
+	h, err := webhook.New(p, opts...)
+	if err != nil {
+		fatal(err)
+	}
+
+	srv, err := server.New(h, cfg.Server.Options()...)
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if err := srv.Run(ctx); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	slog.Error("fatal error", "error", err)
+	os.Exit(1)
+}
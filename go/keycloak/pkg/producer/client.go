@@ -0,0 +1,263 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxResponseSize limits the size of Keycloak responses we are willing to
+// read.
+const maxResponseSize = 1 << 20
+
+// errInvalidCredentials is returned when Keycloak rejects a login.
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// client calls Keycloak admin REST API in a single realm.
+type client struct {
+	base         *url.URL
+	realm        string
+	clientID     string
+	clientSecret string
+	http         *http.Client
+}
+
+// client creates a Keycloak client for the provided payload. Clients of
+// payloads with a CA certificate trust only that CA.
+func (p *Producer) client(pl *Payload) (*client, error) {
+	rawURL := pl.URL
+	if rawURL == "" {
+		rawURL = p.defaultURL
+	}
+
+	if rawURL == "" {
+		return nil, errors.New("url is required, since the producer doesn't have a default one")
+	}
+
+	base, err := url.Parse(strings.TrimSuffix(rawURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("can't parse keycloak url: %w", err)
+	}
+
+	c := &client{
+		base:         base,
+		realm:        pl.Realm,
+		clientID:     pl.ClientID,
+		clientSecret: pl.ClientSecret,
+		http:         p.httpClient,
+	}
+
+	if c.realm == "" {
+		c.realm = defaultRealm
+	}
+
+	if c.clientID == "" {
+		c.clientID = defaultClientID
+	}
+
+	if pl.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(pl.CACert)) {
+			return nil, errors.New("ca_cert doesn't include any PEM certificate")
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if base, ok := p.httpClient.Transport.(*http.Transport); ok {
+			transport = base.Clone()
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+
+		// rotations are rare, so connections aren't reused between them
+		transport.DisableKeepAlives = true
+
+		c.http = &http.Client{Transport: transport, Timeout: p.httpClient.Timeout}
+	}
+
+	return c, nil
+}
+
+// login obtains an access token of the user using the resource owner
+// password grant. It returns errInvalidCredentials if Keycloak rejects the
+// password.
+func (c *client) login(ctx context.Context, user string, password string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "keycloak.login", trace.WithAttributes(
+		attribute.String("realm", c.realm),
+		attribute.String("client_id", c.clientID),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	form := url.Values{
+		"grant_type": {"password"},
+		"client_id":  {c.clientID},
+		"username":   {user},
+		"password":   {password},
+	}
+
+	if c.clientSecret != "" {
+		form.Set("client_secret", c.clientSecret)
+	}
+
+	req, err := c.request(ctx, http.MethodPost, "", strings.NewReader(form.Encode()), "/realms/"+url.PathEscape(c.realm)+"/protocol/openid-connect/token")
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := c.do(req, &res); err != nil {
+		return "", err
+	}
+
+	if res.AccessToken == "" {
+		return "", errors.New("keycloak didn't return an access token")
+	}
+
+	return res.AccessToken, nil
+}
+
+// userID returns the ID of the user with the provided name.
+func (c *client) userID(ctx context.Context, token string, user string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "keycloak.find_user", trace.WithAttributes(attribute.String("realm", c.realm)))
+	defer func() { tracing.End(span, err) }()
+
+	req, err := c.request(ctx, http.MethodGet, token, nil, "/admin/realms/"+url.PathEscape(c.realm)+"/users")
+	if err != nil {
+		return "", err
+	}
+
+	req.URL.RawQuery = url.Values{"username": {user}, "exact": {"true"}}.Encode()
+
+	var users []struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	}
+
+	if err := c.do(req, &users); err != nil {
+		return "", err
+	}
+
+	// older versions ignore exact, and search by prefix
+	for _, u := range users {
+		if strings.EqualFold(u.Username, user) {
+			return u.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("user %s not found in realm %s", user, c.realm)
+}
+
+// resetPassword sets a permanent password of the user.
+func (c *client) resetPassword(ctx context.Context, token string, id string, password string) (err error) {
+	ctx, span := tracer.Start(ctx, "keycloak.reset_password", trace.WithAttributes(attribute.String("realm", c.realm)))
+	defer func() { tracing.End(span, err) }()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"type":      "password",
+		"value":     password,
+		"temporary": false,
+	})
+	if err != nil {
+		return fmt.Errorf("can't marshal credential: %w", err)
+	}
+
+	req, err := c.request(ctx, http.MethodPut, token, bytes.NewReader(body), "/admin/realms/"+url.PathEscape(c.realm)+"/users/"+url.PathEscape(id)+"/reset-password")
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.do(req, nil)
+}
+
+// request creates a request to the provided path, relative to the base URL.
+// Variable path segments must already be escaped.
+func (c *client) request(ctx context.Context, method string, token string, body io.Reader, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.base.String()+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("can't create keycloak request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req, nil
+}
+
+// do sends the request, and decodes the response into out, if set.
+func (c *client) do(req *http.Request, out interface{}) error {
+	res, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("keycloak request failed: %w", err)
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("can't read keycloak response body: %w", err)
+	}
+
+	if res.StatusCode == http.StatusUnauthorized && strings.HasSuffix(req.URL.Path, "/token") {
+		return fmt.Errorf("%w: %s", errInvalidCredentials, message(data))
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected keycloak response code %d: %s", res.StatusCode, message(data))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("can't unmarshal keycloak response: %w", err)
+	}
+
+	return nil
+}
+
+// message returns the error message of a Keycloak response. Token endpoint
+// errors use OAuth 2.0 format, and admin API errors use errorMessage.
+func message(data []byte) string {
+	var res struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+		ErrorMessage     string `json:"errorMessage"`
+	}
+
+	if err := json.Unmarshal(data, &res); err != nil {
+		return "no error message"
+	}
+
+	switch {
+	case res.ErrorDescription != "":
+		return res.ErrorDescription
+	case res.ErrorMessage != "":
+		return res.ErrorMessage
+	case res.Error != "":
+		return res.Error
+	default:
+		return "no error message"
+	}
+}
@@ -0,0 +1,37 @@
+package producer
+
+import "net/http"
+
+// Option is a single configuration parameter used by this producer.
+type Option func(*Producer)
+
+// WithDefaultURL sets the Keycloak URL of payloads that don't include one.
+func WithDefaultURL(url string) Option {
+	return func(p *Producer) {
+		p.defaultURL = url
+	}
+}
+
+// WithPasswordLength sets the length of new passwords. The default is 32.
+func WithPasswordLength(n int) Option {
+	return func(p *Producer) {
+		p.passwordLength = n
+	}
+}
+
+// WithPasswordSymbols includes symbols in new passwords, for realms whose
+// password policy requires them.
+func WithPasswordSymbols(enabled bool) Option {
+	return func(p *Producer) {
+		p.passwordSymbols = enabled
+	}
+}
+
+// WithHTTPClient configures this producer to call Keycloak with the provided
+// client. Its transport is replaced for payloads that include a CA
+// certificate.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Producer) {
+		p.httpClient = c
+	}
+}
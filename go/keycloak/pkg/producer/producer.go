@@ -0,0 +1,179 @@
+// Package producer implements rotation of Keycloak user passwords using
+// Akeyless Rotated Secrets. Passwords are reset through Keycloak admin REST
+// API, and verified by logging in with the new password before it is
+// returned to Akeyless.
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/password"
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	defaultRealm          = "master"
+	defaultClientID       = "admin-cli"
+	defaultPasswordLength = 32
+	defaultTimeout        = 30 * time.Second
+
+	minPasswordLength = 8
+)
+
+var tracer = otel.Tracer("github.com/akeylesslabs/custom-producer/go/keycloak/pkg/producer")
+
+// ErrNotSupported is returned by create and revoke operations, since this
+// producer only rotates passwords.
+var ErrNotSupported = protocol.NewError(protocol.CodeBadRequest, http.StatusBadRequest, "keycloak producer only supports rotation")
+
+// ErrKeycloakFailed is returned when a Keycloak request fails. The
+// underlying error is only logged since it may include internal details.
+var ErrKeycloakFailed = &protocol.Error{
+	Code:      protocol.CodeUpstreamError,
+	Status:    http.StatusBadGateway,
+	Message:   "keycloak request failed",
+	Retryable: true,
+}
+
+// ErrLoginFailed is returned when Keycloak rejects the current password in
+// the payload.
+var ErrLoginFailed = protocol.NewError(protocol.CodePreconditionFailed, http.StatusPreconditionFailed, "keycloak rejected the current password")
+
+// ErrVerifyFailed is returned when Keycloak rejects the new password after
+// resetting it. The previous password is restored, if possible, so the
+// payload in Akeyless stays valid.
+var ErrVerifyFailed = &protocol.Error{
+	Code:      protocol.CodeUpstreamError,
+	Status:    http.StatusBadGateway,
+	Message:   "keycloak rejected the new password",
+	Retryable: true,
+}
+
+// Producer rotates passwords of Keycloak users.
+type Producer struct {
+	defaultURL      string
+	passwordLength  int
+	passwordSymbols bool
+	httpClient      *http.Client
+}
+
+// New creates a new Producer with the provided options.
+func New(opts ...Option) (*Producer, error) {
+	p := &Producer{
+		passwordLength: defaultPasswordLength,
+		httpClient:     &http.Client{Timeout: defaultTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.passwordLength < minPasswordLength {
+		return nil, fmt.Errorf("password length must be at least %d", minPasswordLength)
+	}
+
+	if p.defaultURL != "" && !strings.HasPrefix(p.defaultURL, "https://") {
+		return nil, errors.New("default url must be an https URL, since payloads are sent to it")
+	}
+
+	return p, nil
+}
+
+// Create is not supported.
+func (p *Producer) Create(context.Context, *protocol.CreateRequest) (*protocol.CreateResponse, error) {
+	return nil, ErrNotSupported
+}
+
+// Revoke is not supported.
+func (p *Producer) Revoke(context.Context, *protocol.RevokeRequest) (*protocol.RevokeResponse, error) {
+	return nil, ErrNotSupported
+}
+
+// Rotate resets the password of the user in the payload, and verifies it by
+// logging in with the new password. If Keycloak rejects the new password,
+// the previous one is restored and the rotation fails.
+func (p *Producer) Rotate(ctx context.Context, r *protocol.RotateRequest) (*protocol.RotateResponse, error) {
+	pl, err := payload.Decode[Payload](r.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.client(pl)
+	if err != nil {
+		return nil, payload.ErrInvalidPayload.Wrap(err)
+	}
+
+	token, err := c.login(ctx, pl.User, pl.Password)
+	if errors.Is(err, errInvalidCredentials) {
+		return nil, ErrLoginFailed.Wrap(err)
+	}
+
+	if err != nil {
+		return nil, ErrKeycloakFailed.Wrap(fmt.Errorf("can't log in as %s: %w", pl.User, err))
+	}
+
+	id, err := c.userID(ctx, token, pl.User)
+	if err != nil {
+		return nil, ErrKeycloakFailed.Wrap(err)
+	}
+
+	pass, err := p.generate()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.resetPassword(ctx, token, id, pass); err != nil {
+		return nil, ErrKeycloakFailed.Wrap(fmt.Errorf("can't reset password of %s: %w", pl.User, err))
+	}
+
+	if _, err := c.login(ctx, pl.User, pass); err != nil {
+		log := logging.FromContext(ctx).With("user", pl.User, "realm", c.realm)
+
+		// the token of the previous password is still valid, since
+		// resetting a password doesn't end existing sessions
+		if rerr := c.resetPassword(ctx, token, id, pl.Password); rerr != nil {
+			log.Error("failed to restore previous password", "error", rerr)
+		} else {
+			log.Warn("restored previous password after failed verification")
+		}
+
+		return nil, ErrVerifyFailed.Wrap(err)
+	}
+
+	pl.Password = pass
+
+	out, err := json.Marshal(pl)
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal payload: %w", err)
+	}
+
+	return &protocol.RotateResponse{Payload: string(out)}, nil
+}
+
+// Describe implements protocol.Describer.
+func (p *Producer) Describe() protocol.Description {
+	return protocol.Description{
+		Payload:    Payload{},
+		Operations: []string{protocol.OperationRotate},
+	}
+}
+
+// generate returns a new password of the configured length.
+func (p *Producer) generate() (string, error) {
+	classes := []string{password.Lowercase, password.Uppercase, password.Digits}
+
+	if p.passwordSymbols {
+		classes = append(classes, password.Symbols)
+	}
+
+	return password.Generate(p.passwordLength, classes...)
+}
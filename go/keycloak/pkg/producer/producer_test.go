@@ -0,0 +1,224 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+const (
+	testRealm    = "test"
+	testUser     = "rotated"
+	testPassword = "current-password"
+)
+
+// fakeKeycloak implements the parts of Keycloak REST API used by the
+// producer, for a single realm.
+type fakeKeycloak struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	passwords map[string]string // by user id
+	resets    int
+
+	// rejectNew rejects logins with passwords set after the first one,
+	// like a realm whose password policy breaks verification
+	rejectNew bool
+	initial   string
+}
+
+func newFakeKeycloak(t *testing.T) *fakeKeycloak {
+	t.Helper()
+
+	f := &fakeKeycloak{
+		passwords: map[string]string{"id-" + testUser: testPassword},
+		initial:   testPassword,
+	}
+	f.Server = httptest.NewTLSServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeKeycloak) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	const admin = "/admin/realms/" + testRealm + "/users"
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/realms/"+testRealm+"/protocol/openid-connect/token":
+		pass, ok := f.passwords["id-"+r.PostFormValue("username")]
+		if !ok || pass != r.PostFormValue("password") || r.PostFormValue("grant_type") != "password" ||
+			(f.rejectNew && pass != f.initial) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"Invalid user credentials"}`))
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "token-" + r.PostFormValue("username")})
+	case !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-"):
+		w.WriteHeader(http.StatusUnauthorized)
+	case r.Method == http.MethodGet && r.URL.Path == admin:
+		users := []map[string]string{}
+		if _, ok := f.passwords["id-"+r.URL.Query().Get("username")]; ok {
+			users = append(users, map[string]string{"id": "id-" + r.URL.Query().Get("username"), "username": r.URL.Query().Get("username")})
+		}
+
+		_ = json.NewEncoder(w).Encode(users)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, admin+"/") && strings.HasSuffix(r.URL.Path, "/reset-password"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, admin+"/"), "/reset-password")
+
+		var cred struct {
+			Type      string `json:"type"`
+			Value     string `json:"value"`
+			Temporary bool   `json:"temporary"`
+		}
+
+		if _, ok := f.passwords[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errorMessage":"User not found"}`))
+
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&cred); err != nil || cred.Type != "password" || cred.Value == "" || cred.Temporary {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		f.passwords[id] = cred.Value
+		f.resets++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeKeycloak) password() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.passwords["id-"+testUser]
+}
+
+func (f *fakeKeycloak) resetCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.resets
+}
+
+// payload returns a payload of the fake test user with the password.
+func (f *fakeKeycloak) payload(t *testing.T, password string) string {
+	t.Helper()
+
+	bs, err := json.Marshal(&Payload{URL: f.URL, Realm: testRealm, User: testUser, Password: password})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(bs)
+}
+
+func newTestProducer(t *testing.T, f *fakeKeycloak) *Producer {
+	t.Helper()
+
+	p, err := New(WithHTTPClient(f.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestRotate(t *testing.T) {
+	f := newFakeKeycloak(t)
+	p := newTestProducer(t, f)
+
+	res, err := p.Rotate(context.Background(), &protocol.RotateRequest{Payload: f.payload(t, testPassword)})
+	if err != nil {
+		t.Fatalf("Rotate() failed: %v", err)
+	}
+
+	var pl Payload
+	if err := json.Unmarshal([]byte(res.Payload), &pl); err != nil {
+		t.Fatalf("Rotate() returned an invalid payload: %v", err)
+	}
+
+	if pl.Password == testPassword || len(pl.Password) != defaultPasswordLength {
+		t.Errorf("Rotate() returned password %q", pl.Password)
+	}
+
+	if pass := f.password(); pass != pl.Password {
+		t.Errorf("keycloak has password %q, want the returned one", pass)
+	}
+
+	if pl.URL != f.URL || pl.Realm != testRealm || pl.User != testUser {
+		t.Errorf("Rotate() changed other payload fields: %+v", pl)
+	}
+
+	// the previous password no longer works, so rotating it again fails
+	_, err = p.Rotate(context.Background(), &protocol.RotateRequest{Payload: f.payload(t, testPassword)})
+	if !errors.Is(err, ErrLoginFailed) {
+		t.Errorf("Rotate() with the previous password = %v, want %v", err, ErrLoginFailed)
+	}
+}
+
+func TestRotateLoginFailed(t *testing.T) {
+	f := newFakeKeycloak(t)
+	p := newTestProducer(t, f)
+
+	_, err := p.Rotate(context.Background(), &protocol.RotateRequest{Payload: f.payload(t, "wrong")})
+	if !errors.Is(err, ErrLoginFailed) {
+		t.Fatalf("Rotate() = %v, want %v", err, ErrLoginFailed)
+	}
+
+	if n := f.resetCount(); n != 0 {
+		t.Errorf("password was reset %d times", n)
+	}
+}
+
+func TestRotateRestoresPassword(t *testing.T) {
+	f := newFakeKeycloak(t)
+	f.rejectNew = true
+	p := newTestProducer(t, f)
+
+	_, err := p.Rotate(context.Background(), &protocol.RotateRequest{Payload: f.payload(t, testPassword)})
+	if !errors.Is(err, ErrVerifyFailed) {
+		t.Fatalf("Rotate() = %v, want %v", err, ErrVerifyFailed)
+	}
+
+	if pass := f.password(); pass != testPassword {
+		t.Errorf("keycloak has password %q, want the previous one", pass)
+	}
+
+	if n := f.resetCount(); n != 2 {
+		t.Errorf("password was reset %d times, want 2", n)
+	}
+}
+
+func TestCreateAndRevokeNotSupported(t *testing.T) {
+	f := newFakeKeycloak(t)
+	p := newTestProducer(t, f)
+
+	if _, err := p.Create(context.Background(), &protocol.CreateRequest{Payload: f.payload(t, testPassword)}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Create() = %v, want %v", err, ErrNotSupported)
+	}
+
+	if _, err := p.Revoke(context.Background(), &protocol.RevokeRequest{Payload: f.payload(t, testPassword)}); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("Revoke() = %v, want %v", err, ErrNotSupported)
+	}
+
+	if ops := p.Describe().Operations; len(ops) != 1 || ops[0] != protocol.OperationRotate {
+		t.Errorf("Describe() advertises %v, want only rotate", ops)
+	}
+}
@@ -0,0 +1,19 @@
+package producer
+
+// Payload is the rotated secret payload configured in Akeyless. It holds the
+// credentials of the Keycloak user whose password is rotated, so it should
+// be [encrypted]. Realm and ClientID default to "master" and "admin-cli".
+//
+// Payloads of the custom server script, {"user": ..., "password": ...}, are
+// compatible with this producer if it is configured with a default URL.
+//
+// [encrypted]: https://github.com/akeylesslabs/custom-producer/tree/master/go#payloads
+type Payload struct {
+	URL          string `json:"url,omitempty" pattern:"https://[^/?#]+(/[^?#]*)?" description:"Keycloak URL, including /auth for versions before 17. Defaults to the URL configured in the producer"`
+	Realm        string `json:"realm,omitempty" description:"Realm of the user, master by default"`
+	ClientID     string `json:"client_id,omitempty" description:"Client used to log in, admin-cli by default. It must allow direct access grants"`
+	ClientSecret string `json:"client_secret,omitempty" description:"Secret of confidential clients"`
+	User         string `json:"user" validate:"required" description:"User whose password is rotated. It must be allowed to reset its password, for example, with the manage-users role"`
+	Password     string `json:"password" validate:"required" description:"Current password of the user"`
+	CACert       string `json:"ca_cert,omitempty" description:"PEM encoded CA certificates that issued the Keycloak certificate, if it isn't trusted by the system"`
+}
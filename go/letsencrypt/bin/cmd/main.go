@@ -1,31 +1,324 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"context"
+	"flag"
+	"log/slog"
+	"net/url"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/akeylesslabs/custom-producer/go/letsencrypt/internal/producer"
-	"github.com/akeylesslabs/custom-producer/go/letsencrypt/internal/webhook"
+	"github.com/akeylesslabs/custom-producer/go/letsencrypt/internal/config"
+	"github.com/akeylesslabs/custom-producer/go/letsencrypt/pkg/producer"
+	"github.com/akeylesslabs/custom-producer/go/pkg/audit"
+	pkgconfig "github.com/akeylesslabs/custom-producer/go/pkg/config"
+	"github.com/akeylesslabs/custom-producer/go/pkg/idempotency"
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/ratelimit"
+	"github.com/akeylesslabs/custom-producer/go/pkg/replay"
+	"github.com/akeylesslabs/custom-producer/go/pkg/server"
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"github.com/akeylesslabs/custom-producer/go/pkg/webhook"
 )
 
 func main() {
-	p, err := producer.New(
-		producer.WithDryRunEmail(os.Getenv("LE_DRY_RUN_EMAIL")),
-		producer.WithDryRunDomain(os.Getenv("LE_DRY_RUN_DOMAIN")),
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of a YAML, TOML or JSON config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fatal(err)
+	}
+
+	slog.SetDefault(logging.New(os.Stderr, logging.ParseLevel(cfg.Log.Level)))
+
+	shutdownTracing, err := tracing.Setup(
+		context.Background(),
+		tracing.WithExporter(cfg.Tracing.Exporter),
+		tracing.WithServiceName("letsencrypt-producer"),
 	)
 	if err != nil {
-		log.Fatalln(err)
+		fatal(err)
 	}
 
-	h, err := webhook.New(
-		p,
-		webhook.WithAllowedAccessID(os.Getenv("AKEYLESS_ACCESS_ID")),
-		webhook.WithAllowedItemName(os.Getenv("AKEYLESS_ITEM_NAME")),
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
+
+	p, err := producer.New(
+		producer.WithDefaultEmail(cfg.LetsEncrypt.Email),
+		producer.WithDryRunEmail(cfg.LetsEncrypt.DryRunEmail),
+		producer.WithDryRunDomain(cfg.LetsEncrypt.DryRunDomain),
+		producer.WithPreflightChecks(cfg.LetsEncrypt.PreflightChecks),
+		producer.WithNameservers(cfg.LetsEncrypt.Nameservers...),
 	)
 	if err != nil {
-		log.Fatalln(err)
+		fatal(err)
+	}
+
+	// state is shared by every webhook created on reload, so that limits and
+	// idempotent responses survive it
+	st := &state{
+		limiters:    make(map[string]*ratelimit.Memory),
+		idempotency: idempotency.NewMemory(),
+		replay:      replay.NewMemory(),
+	}
+
+	if n := cfg.RateLimits.MaxConcurrentOrders; n > 0 {
+		st.concurrency = ratelimit.NewSemaphore(n)
+	}
+
+	if st.auditLogger = newAuditLogger(cfg.Audit); st.auditLogger != nil {
+		defer func() { _ = st.auditLogger.Close() }()
+	}
+
+	h, err := webhook.NewReloadable(p, st.hookOptions(cfg)...)
+	if err != nil {
+		fatal(err)
+	}
+
+	srv, err := server.New(h, serverOptions(cfg.Server)...)
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	go pkgconfig.Watch(ctx, *configPath, pkgconfig.DefaultWatchInterval, func() {
+		next, err := config.Load(*configPath)
+		if err != nil {
+			slog.Error("can't reload config, keeping the current one", "error", err)
+			return
+		}
+
+		if fields := cfg.RestartRequired(next); len(fields) > 0 {
+			slog.Warn("config changes that require a restart weren't applied", "fields", fields)
+		}
+
+		// only policy fields are applied, everything else keeps its
+		// value until restart
+		applied := *cfg
+		applied.Akeyless = next.Akeyless
+		applied.RateLimits = next.RateLimits
+		applied.RateLimits.MaxConcurrentOrders = cfg.RateLimits.MaxConcurrentOrders
+
+		if err := h.Reload(st.hookOptions(&applied)...); err != nil {
+			slog.Error("can't apply reloaded config", "error", err)
+			return
+		}
+
+		cfg = &applied
+
+		slog.Info("config reloaded")
+	})
+
+	if err := srv.Run(ctx); err != nil {
+		fatal(err)
+	}
+}
+
+type state struct {
+	limiters    map[string]*ratelimit.Memory
+	concurrency ratelimit.Concurrency
+	idempotency idempotency.Store
+	replay      replay.Store
+	auditLogger *audit.Logger
+}
+
+func (st *state) hookOptions(cfg *config.Config) []webhook.Option {
+	opts := []webhook.Option{
+		webhook.WithAllowedAccessID(cfg.Akeyless.AccessID),
+		webhook.WithAllowedItemName(cfg.Akeyless.ItemName),
+	}
+
+	if cfg.Server.MaxBodySize > 0 {
+		opts = append(opts, webhook.WithMaxBodySize(cfg.Server.MaxBodySize))
+	}
+
+	if cfg.Server.StrictDecoding {
+		opts = append(opts, webhook.WithStrictDecoding())
+	}
+
+	keys := map[string]webhook.KeyFunc{
+		"access_id": webhook.ByAccessID(),
+		"item_name": webhook.ByItemName(cfg.Akeyless.ItemName),
+		"sub_claim": webhook.BySubClaim(cfg.RateLimits.SubClaimName),
+		"domain":    webhook.ByInputField("domain"),
+	}
+
+	for _, rule := range cfg.RateLimits.Rules() {
+		if rule.Rate == "" {
+			continue
+		}
+
+		// rates were validated when the config was loaded
+		rate, _ := ratelimit.ParseRate(rule.Rate)
+
+		// limiters are reused as long as their rate doesn't change
+		id := rule.Name + "=" + rule.Rate
+
+		l, ok := st.limiters[id]
+		if !ok {
+			l = ratelimit.NewMemory(rate)
+			st.limiters[id] = l
+		}
+
+		opts = append(opts, webhook.WithRateLimit(rule.Name, l, keys[rule.Name]))
+	}
+
+	if st.concurrency != nil {
+		opts = append(opts, webhook.WithConcurrencyLimit(st.concurrency))
+	}
+
+	if w := time.Duration(cfg.IdempotencyWindow); w > 0 {
+		opts = append(opts, webhook.WithIdempotency(st.idempotency, w))
+	}
+
+	if cfg.ResponseEncryption != "" {
+		opts = append(opts, webhook.WithResponseEncryption(cfg.ResponseEncryption == "required"))
+	}
+
+	if len(cfg.PayloadKeys) > 0 {
+		keys := make([][]byte, 0, len(cfg.PayloadKeys))
+
+		for _, k := range cfg.PayloadKeys {
+			// keys were validated when the config was loaded
+			key, _ := payload.ParseKey(k)
+			keys = append(keys, key)
+		}
+
+		opts = append(opts, webhook.WithPayloadKeys(keys...))
+	}
+
+	if w := time.Duration(cfg.ReplayWindow); w > 0 {
+		opts = append(opts, webhook.WithReplayGuard(st.replay, w))
+	}
+
+	if st.auditLogger != nil {
+		opts = append(opts, webhook.WithAuditLogger(st.auditLogger))
+	}
+
+	return opts
+}
+
+func serverOptions(cfg config.Server) []server.Option {
+	var opts []server.Option
+
+	listeners := cfg.Listeners
+
+	// restrictions of the server apply to the default address too
+	addrs := cfg.ListenAddr
+	if len(addrs) == 0 && len(listeners) == 0 {
+		addrs = []string{":80"}
+	}
+
+	for _, addr := range addrs {
+		listeners = append(listeners, config.Listener{
+			Addr:               addr,
+			ClientCAFile:       cfg.ClientCAFile,
+			AllowedClientNames: cfg.AllowedClientNames,
+			AllowedCIDRs:       cfg.AllowedCIDRs,
+		})
+	}
+
+	for _, l := range listeners {
+		var lopts []server.ListenerOption
+
+		if l.ClientCAFile != "" {
+			lopts = append(lopts, server.WithClientCA(l.ClientCAFile), server.WithAllowedClientNames(l.AllowedClientNames...))
+		}
+
+		if len(l.AllowedCIDRs) > 0 {
+			lopts = append(lopts, server.WithAllowedCIDRs(l.AllowedCIDRs...))
+		}
+
+		opts = append(opts, server.WithListener(l.Addr, lopts...))
+	}
+
+	durations := []struct {
+		value pkgconfig.Duration
+		opt   func(time.Duration) server.Option
+	}{
+		{cfg.ReadHeaderTimeout, server.WithReadHeaderTimeout},
+		{cfg.ReadTimeout, server.WithReadTimeout},
+		{cfg.WriteTimeout, server.WithWriteTimeout},
+		{cfg.IdleTimeout, server.WithIdleTimeout},
+		{cfg.ShutdownTimeout, server.WithShutdownTimeout},
+	}
+
+	for _, d := range durations {
+		if d.value > 0 {
+			opts = append(opts, d.opt(time.Duration(d.value)))
+		}
+	}
+
+	if cfg.TLSCertFile != "" {
+		opts = append(opts, server.WithTLSCertificate(cfg.TLSCertFile, cfg.TLSKeyFile))
+	} else if len(cfg.TLSSelfIssuedHosts) > 0 {
+		opts = append(opts, server.WithSelfIssuedCertificate(cfg.TLSSelfIssuedHosts...))
+	}
+
+	return opts
+}
+
+func newAuditLogger(cfg config.Audit) *audit.Logger {
+	var sinks audit.MultiSink
+
+	if cfg.File != "" {
+		s, err := audit.NewFileSink(cfg.File)
+		if err != nil {
+			fatal(err)
+		}
+
+		sinks = append(sinks, s)
+	}
+
+	if addr := cfg.Syslog; addr != "" {
+		var network, address string
+
+		if addr != "local" {
+			// the address was validated when the config was loaded
+			u, _ := url.Parse(addr)
+			network, address = u.Scheme, u.Host
+		}
+
+		s, err := audit.NewSyslogSink(network, address, "akeyless-producer")
+		if err != nil {
+			fatal(err)
+		}
+
+		sinks = append(sinks, s)
+	}
+
+	if cfg.WebhookURL != "" {
+		sinks = append(sinks, audit.NewWebhookSink(cfg.WebhookURL))
+	}
+
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	var opts []audit.Option
+
+	if len(cfg.SubClaims) > 0 {
+		opts = append(opts, audit.WithSubClaims(cfg.SubClaims...))
+	}
+
+	l, err := audit.New(sinks, opts...)
+	if err != nil {
+		fatal(err)
 	}
 
-	log.Fatalln(http.ListenAndServe(":80", h))
+	return l
+}
+
+func fatal(err error) {
+	slog.Error("fatal error", "error", err)
+	os.Exit(1)
 }
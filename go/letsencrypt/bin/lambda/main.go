@@ -4,22 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 
-	"github.com/akeylesslabs/custom-producer/go/letsencrypt/internal/producer"
+	"github.com/akeylesslabs/custom-producer/go/letsencrypt/pkg/producer"
 	"github.com/akeylesslabs/custom-producer/go/pkg/auth"
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
 func handleRequest(ctx context.Context, r events.APIGatewayV2HTTPRequest) (interface{}, error) {
-	log.Println("new request:", r.RawPath, r.Body)
+	slog.Info("new request", "path", r.RawPath, "headers", r.Headers, "body", json.RawMessage(r.Body))
 
 	p, err := producer.New(
+		producer.WithDefaultEmail(os.Getenv("LE_EMAIL")),
 		producer.WithDryRunEmail(os.Getenv("LE_DRY_RUN_EMAIL")),
 		producer.WithDryRunDomain(os.Getenv("LE_DRY_RUN_DOMAIN")),
+		producer.WithPreflightChecks(os.Getenv("LE_PREFLIGHT_CHECKS") == "true"),
+		producer.WithNameservers(strings.Split(os.Getenv("LE_NAMESERVERS"), ",")...),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("can't setup producer: %w", err)
@@ -31,24 +36,25 @@ func handleRequest(ctx context.Context, r events.APIGatewayV2HTTPRequest) (inter
 
 	switch strings.TrimSuffix(r.RawPath, "/") {
 	case "/sync/create":
-		var cr *producer.CreateRequest
+		var cr protocol.CreateRequest
 		if err := json.Unmarshal([]byte(r.Body), &cr); err != nil {
 			return nil, fmt.Errorf("invalid request: %w", err)
 		}
 
-		return p.Create(cr)
+		return p.Create(ctx, &cr)
 	case "/sync/revoke":
-		var rr *producer.RevokeRequest
+		var rr protocol.RevokeRequest
 		if err := json.Unmarshal([]byte(r.Body), &rr); err != nil {
 			return nil, fmt.Errorf("invalid request: %w", err)
 		}
 
-		return p.Revoke(rr)
+		return p.Revoke(ctx, &rr)
 	default:
 		return nil, fmt.Errorf("invalid request path '%s'", r.RawPath)
 	}
 }
 
 func main() {
+	slog.SetDefault(logging.New(os.Stderr, logging.ParseLevel(os.Getenv("LOG_LEVEL"))))
 	lambda.Start(handleRequest)
 }
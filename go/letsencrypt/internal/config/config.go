@@ -0,0 +1,267 @@
+// Package config defines configuration of Let's Encrypt producer. It is
+// loaded from a YAML, TOML or JSON file, and every field can be overridden
+// with the environment variable named in its `env` tag.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/config"
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/ratelimit"
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+)
+
+// Config is the complete configuration of the producer server.
+type Config struct {
+	Akeyless    Akeyless    `yaml:"akeyless" toml:"akeyless" json:"akeyless"`
+	LetsEncrypt LetsEncrypt `yaml:"letsencrypt" toml:"letsencrypt" json:"letsencrypt"`
+	Server      Server      `yaml:"server" toml:"server" json:"server"`
+	RateLimits  RateLimits  `yaml:"rate_limits" toml:"rate_limits" json:"rate_limits"`
+	Audit       Audit       `yaml:"audit" toml:"audit" json:"audit"`
+	Log         Log         `yaml:"log" toml:"log" json:"log"`
+	Tracing     Tracing     `yaml:"tracing" toml:"tracing" json:"tracing"`
+
+	// IdempotencyWindow enables idempotent create requests if set.
+	IdempotencyWindow config.Duration `yaml:"idempotency_window" toml:"idempotency_window" json:"idempotency_window" env:"IDEMPOTENCY_WINDOW"`
+
+	// ResponseEncryption is "optional" or "required" to encrypt certificates
+	// to a public key provided in the input.
+	ResponseEncryption string `yaml:"response_encryption" toml:"response_encryption" json:"response_encryption" env:"RESPONSE_ENCRYPTION"`
+
+	// PayloadKeys are base64 encoded AES-256 keys that decrypt producer
+	// payloads. If set, plain payloads are rejected.
+	PayloadKeys []string `yaml:"payload_keys" toml:"payload_keys" json:"payload_keys" env:"PAYLOAD_KEYS"`
+
+	// ReplayWindow enables rejection of replayed credentials if set.
+	ReplayWindow config.Duration `yaml:"replay_window" toml:"replay_window" json:"replay_window" env:"REPLAY_WINDOW"`
+}
+
+// Akeyless restricts which producers may call this server. These fields can
+// be reloaded.
+type Akeyless struct {
+	AccessID string `yaml:"access_id" toml:"access_id" json:"access_id" env:"AKEYLESS_ACCESS_ID"`
+	ItemName string `yaml:"item_name" toml:"item_name" json:"item_name" env:"AKEYLESS_ITEM_NAME"`
+}
+
+// LetsEncrypt configures the producer itself.
+type LetsEncrypt struct {
+	Email           string   `yaml:"email" toml:"email" json:"email" env:"LE_EMAIL"`
+	DryRunEmail     string   `yaml:"dry_run_email" toml:"dry_run_email" json:"dry_run_email" env:"LE_DRY_RUN_EMAIL"`
+	DryRunDomain    string   `yaml:"dry_run_domain" toml:"dry_run_domain" json:"dry_run_domain" env:"LE_DRY_RUN_DOMAIN"`
+	PreflightChecks bool     `yaml:"preflight_checks" toml:"preflight_checks" json:"preflight_checks" env:"LE_PREFLIGHT_CHECKS"`
+	Nameservers     []string `yaml:"nameservers" toml:"nameservers" json:"nameservers" env:"LE_NAMESERVERS"`
+}
+
+// Server configures the HTTP server. Zero values use the server defaults.
+type Server struct {
+	ListenAddr         []string        `yaml:"listen_addr" toml:"listen_addr" json:"listen_addr" env:"LISTEN_ADDR"`
+	ReadHeaderTimeout  config.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" json:"read_header_timeout" env:"READ_HEADER_TIMEOUT"`
+	ReadTimeout        config.Duration `yaml:"read_timeout" toml:"read_timeout" json:"read_timeout" env:"READ_TIMEOUT"`
+	WriteTimeout       config.Duration `yaml:"write_timeout" toml:"write_timeout" json:"write_timeout" env:"WRITE_TIMEOUT"`
+	IdleTimeout        config.Duration `yaml:"idle_timeout" toml:"idle_timeout" json:"idle_timeout" env:"IDLE_TIMEOUT"`
+	ShutdownTimeout    config.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	TLSCertFile        string          `yaml:"tls_cert_file" toml:"tls_cert_file" json:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile         string          `yaml:"tls_key_file" toml:"tls_key_file" json:"tls_key_file" env:"TLS_KEY_FILE"`
+	TLSSelfIssuedHosts []string        `yaml:"tls_self_issued_hosts" toml:"tls_self_issued_hosts" json:"tls_self_issued_hosts" env:"TLS_SELF_ISSUED_HOSTS"`
+	ClientCAFile       string          `yaml:"client_ca_file" toml:"client_ca_file" json:"client_ca_file" env:"CLIENT_CA_FILE"`
+	AllowedClientNames []string        `yaml:"allowed_client_names" toml:"allowed_client_names" json:"allowed_client_names" env:"ALLOWED_CLIENT_NAMES"`
+	AllowedCIDRs       []string        `yaml:"allowed_cidrs" toml:"allowed_cidrs" json:"allowed_cidrs" env:"ALLOWED_CIDRS"`
+	Listeners          []Listener      `yaml:"listeners" toml:"listeners" json:"listeners"`
+	MaxBodySize        int64           `yaml:"max_body_size" toml:"max_body_size" json:"max_body_size" env:"MAX_BODY_SIZE"`
+	StrictDecoding     bool            `yaml:"strict_decoding" toml:"strict_decoding" json:"strict_decoding" env:"STRICT_DECODING"`
+}
+
+// Listener is a listen address with its own access restrictions. Addresses
+// in Server.ListenAddr use the restrictions of Server instead.
+type Listener struct {
+	Addr               string   `yaml:"addr" toml:"addr" json:"addr"`
+	ClientCAFile       string   `yaml:"client_ca_file" toml:"client_ca_file" json:"client_ca_file"`
+	AllowedClientNames []string `yaml:"allowed_client_names" toml:"allowed_client_names" json:"allowed_client_names"`
+	AllowedCIDRs       []string `yaml:"allowed_cidrs" toml:"allowed_cidrs" json:"allowed_cidrs"`
+}
+
+// RateLimits configures limits of create requests. Rates use
+// ratelimit.ParseRate format, for example, "5/h". These fields can be
+// reloaded, except MaxConcurrentOrders.
+type RateLimits struct {
+	AccessID            string `yaml:"access_id" toml:"access_id" json:"access_id" env:"RATE_LIMIT_ACCESS_ID"`
+	ItemName            string `yaml:"item_name" toml:"item_name" json:"item_name" env:"RATE_LIMIT_ITEM_NAME"`
+	SubClaim            string `yaml:"sub_claim" toml:"sub_claim" json:"sub_claim" env:"RATE_LIMIT_SUB_CLAIM"`
+	SubClaimName        string `yaml:"sub_claim_name" toml:"sub_claim_name" json:"sub_claim_name" env:"RATE_LIMIT_SUB_CLAIM_NAME"`
+	Domain              string `yaml:"domain" toml:"domain" json:"domain" env:"RATE_LIMIT_DOMAIN"`
+	MaxConcurrentOrders int    `yaml:"max_concurrent_orders" toml:"max_concurrent_orders" json:"max_concurrent_orders" env:"MAX_CONCURRENT_ORDERS"`
+}
+
+// RateRule is a single configured rate, named after the key it limits.
+type RateRule struct {
+	Name string
+	Rate string
+}
+
+// Rules lists every rate limit, including unset ones, in a stable order.
+func (r RateLimits) Rules() []RateRule {
+	return []RateRule{
+		{Name: "access_id", Rate: r.AccessID},
+		{Name: "item_name", Rate: r.ItemName},
+		{Name: "sub_claim", Rate: r.SubClaim},
+		{Name: "domain", Rate: r.Domain},
+	}
+}
+
+// Audit configures audit log sinks. More than one sink may be used.
+type Audit struct {
+	File       string   `yaml:"file" toml:"file" json:"file" env:"AUDIT_LOG_FILE"`
+	Syslog     string   `yaml:"syslog" toml:"syslog" json:"syslog" env:"AUDIT_SYSLOG"`
+	WebhookURL string   `yaml:"webhook_url" toml:"webhook_url" json:"webhook_url" env:"AUDIT_WEBHOOK_URL"`
+	SubClaims  []string `yaml:"sub_claims" toml:"sub_claims" json:"sub_claims" env:"AUDIT_SUB_CLAIMS"`
+}
+
+// Log configures logging.
+type Log struct {
+	Level string `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL"`
+}
+
+// Tracing configures OpenTelemetry tracing.
+type Tracing struct {
+	Exporter string `yaml:"exporter" toml:"exporter" json:"exporter" env:"OTEL_TRACES_EXPORTER"`
+}
+
+// Load loads configuration from the file at path, which may be empty, and
+// from environment variables.
+func Load(path string) (*Config, error) {
+	var c Config
+	if err := config.Load(path, &c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Validate implements config.Validator. Every problem is reported at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Akeyless.AccessID == "" {
+		errs = append(errs, errors.New("akeyless.access_id is required"))
+	}
+
+	for _, email := range []string{c.LetsEncrypt.Email, c.LetsEncrypt.DryRunEmail} {
+		if email == "" {
+			continue
+		}
+
+		if _, err := mail.ParseAddress(email); err != nil {
+			errs = append(errs, fmt.Errorf("invalid email '%s': %w", email, err))
+		}
+	}
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, errors.New("server.tls_cert_file and server.tls_key_file must be set together"))
+	}
+
+	tlsEnabled := c.Server.TLSCertFile != "" || len(c.Server.TLSSelfIssuedHosts) > 0
+
+	listeners := append([]Listener{{
+		Addr:               "server",
+		ClientCAFile:       c.Server.ClientCAFile,
+		AllowedClientNames: c.Server.AllowedClientNames,
+	}}, c.Server.Listeners...)
+
+	for _, l := range listeners {
+		if l.ClientCAFile != "" && !tlsEnabled {
+			errs = append(errs, fmt.Errorf("client_ca_file of %s requires TLS", l.Addr))
+		}
+
+		if len(l.AllowedClientNames) > 0 && l.ClientCAFile == "" {
+			errs = append(errs, fmt.Errorf("allowed_client_names of %s require client_ca_file", l.Addr))
+		}
+	}
+
+	if c.Server.MaxBodySize < 0 {
+		errs = append(errs, errors.New("server.max_body_size must not be negative"))
+	}
+
+	for _, rate := range c.RateLimits.Rules() {
+		if rate.Rate == "" {
+			continue
+		}
+
+		if _, err := ratelimit.ParseRate(rate.Rate); err != nil {
+			errs = append(errs, fmt.Errorf("rate_limits.%s: %w", rate.Name, err))
+		}
+	}
+
+	if c.RateLimits.SubClaim != "" && c.RateLimits.SubClaimName == "" {
+		errs = append(errs, errors.New("rate_limits.sub_claim_name is required with rate_limits.sub_claim"))
+	}
+
+	if c.RateLimits.MaxConcurrentOrders < 0 {
+		errs = append(errs, errors.New("rate_limits.max_concurrent_orders must not be negative"))
+	}
+
+	if s := c.Audit.Syslog; s != "" && s != "local" {
+		if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("audit.syslog must be 'local' or an address such as udp://host:514, got '%s'", s))
+		}
+	}
+
+	for i, key := range c.PayloadKeys {
+		if _, err := payload.ParseKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("payload_keys[%d]: %w", i, err))
+		}
+	}
+
+	switch c.ResponseEncryption {
+	case "", "optional", "required":
+	default:
+		errs = append(errs, fmt.Errorf("response_encryption must be 'optional' or 'required', got '%s'", c.ResponseEncryption))
+	}
+
+	switch c.Tracing.Exporter {
+	case "", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		errs = append(errs, fmt.Errorf("unknown tracing.exporter '%s'", c.Tracing.Exporter))
+	}
+
+	return errors.Join(errs...)
+}
+
+// RestartRequired lists the fields, by their path in the config file, that
+// differ between c and other and can't be applied without a restart. Only
+// the allowed access ID, item name and rate limits, except
+// max_concurrent_orders, can be reloaded. Listeners and their allowlists
+// (allowed_cidrs, allowed_client_names and client_ca_file) are restart-only.
+func (c *Config) RestartRequired(other *Config) []string {
+	a, b := *c, *other
+
+	a.Akeyless, b.Akeyless = Akeyless{}, Akeyless{}
+	a.RateLimits, b.RateLimits = RateLimits{MaxConcurrentOrders: a.RateLimits.MaxConcurrentOrders}, RateLimits{MaxConcurrentOrders: b.RateLimits.MaxConcurrentOrders}
+
+	return changedFields("", reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+// changedFields compares two structs field by field, and descends into
+// nested structs so that changes are reported as precisely as possible.
+func changedFields(prefix string, a, b reflect.Value) []string {
+	var changed []string
+
+	for i := 0; i < a.NumField(); i++ {
+		f := a.Type().Field(i)
+		name := prefix + strings.Split(f.Tag.Get("yaml"), ",")[0]
+
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			changed = append(changed, changedFields(name+".", a.Field(i), b.Field(i))...)
+		case !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()):
+			changed = append(changed, name)
+		}
+	}
+
+	return changed
+}
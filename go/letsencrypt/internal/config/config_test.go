@@ -0,0 +1,59 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/config"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"valid", func(*Config) {}, ""},
+		{"missing access id", func(c *Config) { c.Akeyless.AccessID = "" }, "akeyless.access_id is required"},
+		{"invalid email", func(c *Config) { c.LetsEncrypt.Email = "admin" }, "invalid email"},
+		{"invalid rate", func(c *Config) { c.RateLimits.AccessID = "10/week" }, "rate_limits.access_id"},
+		{"sub-claim rate without name", func(c *Config) { c.RateLimits.SubClaim = "5/h" }, "rate_limits.sub_claim_name is required"},
+		{"certificate without key", func(c *Config) { c.Server.TLSCertFile = "cert.pem" }, "must be set together"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{Akeyless: Akeyless{AccessID: "p-test"}}
+			tt.modify(c)
+
+			err := c.Validate()
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate() failed: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	old := &Config{Akeyless: Akeyless{AccessID: "p-old"}}
+
+	c := *old
+	c.Akeyless.AccessID = "p-new"
+	c.RateLimits.AccessID = "5/h"
+	c.RateLimits.MaxConcurrentOrders = 2
+	c.LetsEncrypt.Email = "admin@example.com"
+	c.Server.WriteTimeout = config.Duration(time.Minute)
+
+	// reloadable fields aren't reported
+	want := []string{"letsencrypt.email", "server.write_timeout", "rate_limits.max_concurrent_orders"}
+
+	if got := c.RestartRequired(old); !slices.Equal(got, want) {
+		t.Errorf("RestartRequired() = %v, want %v", got, want)
+	}
+}
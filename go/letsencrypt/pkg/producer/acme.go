@@ -0,0 +1,83 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"github.com/go-acme/lego/v4/acme"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxDirectorySize limits the size of ACME directory documents we are willing
+// to read.
+const maxDirectorySize = 1 << 20
+
+// fetchDirectory retrieves the ACME directory object of the CA. The directory
+// describes the server policy, for example, the certificate profiles that can
+// be requested.
+func fetchDirectory(ctx context.Context, client *http.Client, dirURL string) (_ *acme.Directory, err error) {
+	ctx, span := tracer.Start(ctx, "acme.directory", trace.WithAttributes(attribute.String("url", dirURL)))
+	defer func() { tracing.End(span, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dirURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't create directory request: %w", err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("directory request failed: %w", err)
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxDirectorySize))
+	if err != nil {
+		return nil, fmt.Errorf("can't read directory response body: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected directory response code %d: %s", res.StatusCode, string(body))
+	}
+
+	var dir acme.Directory
+	if err := json.Unmarshal(body, &dir); err != nil {
+		return nil, fmt.Errorf("can't unmarshal directory '%s': %w", string(body), err)
+	}
+
+	return &dir, nil
+}
+
+// validateInput makes sure that the requested certificate options are allowed
+// by the CA before an order is placed, so that obviously invalid requests
+// don't count against Let's Encrypt rate limits.
+func validateInput(inp Input, dir *acme.Directory) error {
+	if inp.Profile == "" {
+		return nil
+	}
+
+	if len(dir.Meta.Profiles) == 0 {
+		return ErrInvalidInput.WithMessage("the CA doesn't support certificate profiles")
+	}
+
+	if _, ok := dir.Meta.Profiles[inp.Profile]; !ok {
+		profiles := make([]string, 0, len(dir.Meta.Profiles))
+		for name := range dir.Meta.Profiles {
+			profiles = append(profiles, name)
+		}
+
+		sort.Strings(profiles)
+
+		return ErrInvalidInput.WithMessage("unknown profile '%s', supported profiles: %s",
+			inp.Profile, strings.Join(profiles, ", "))
+	}
+
+	return nil
+}
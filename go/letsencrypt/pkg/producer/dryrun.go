@@ -0,0 +1,214 @@
+package producer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/registration"
+)
+
+// dryRunCheckTimeout limits the duration of each dry-run check, so that a dry
+// run doesn't wait for the full timeout of an unresponsive dependency.
+const dryRunCheckTimeout = 10 * time.Second
+
+// Names of the individual dry-run and readiness checks.
+const (
+	CheckACMEDirectory  = "acme_directory"
+	CheckDNSCredentials = "dns_credentials"
+	CheckDNSProvider    = "dns_provider"
+	CheckAccount        = "account"
+	CheckDryRunDomain   = "dry_run_domain"
+)
+
+// Statuses of a single dry-run health check.
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// HealthCheck is the result of a single dry-run check.
+type HealthCheck struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// HealthReport is returned in response to dry-run requests. It is healthy
+// only if none of its checks failed; skipped checks don't affect it.
+type HealthReport struct {
+	Healthy bool          `json:"healthy"`
+	Checks  []HealthCheck `json:"checks"`
+}
+
+// ErrDryRunFailed is returned when at least one dry-run check fails. Its
+// details include the full health report.
+var ErrDryRunFailed = protocol.NewError(protocol.CodeUnavailable, http.StatusServiceUnavailable, "dry run failed")
+
+// errSkipped is returned by dry-run checks that can't run because the
+// producer isn't configured for them.
+var errSkipped = errors.New("skipped")
+
+// dryRun verifies that this deployment is able to obtain certificates without
+// actually obtaining one. It uses the dry-run email and domain configured with
+// WithDryRunEmail and WithDryRunDomain. Each check must finish within
+// dryRunCheckTimeout.
+func (p *producer) dryRun(ctx context.Context) (*HealthReport, error) {
+	report := &HealthReport{Healthy: true}
+
+	run := func(name string, check func(context.Context) error) {
+		ctx, cancel := context.WithTimeout(ctx, dryRunCheckTimeout)
+		defer cancel()
+
+		start := time.Now()
+		err := check(ctx)
+
+		c := HealthCheck{Name: name, Status: StatusOK, Duration: time.Since(start).String()}
+
+		switch {
+		case errors.Is(err, errSkipped):
+			c.Status = StatusSkipped
+			c.Error = err.Error()
+		case err != nil:
+			c.Status = StatusFailed
+			c.Error = err.Error()
+			report.Healthy = false
+		}
+
+		report.Checks = append(report.Checks, c)
+	}
+
+	run(CheckACMEDirectory, p.checkDirectory)
+	run(CheckDNSCredentials, p.checkDNSCredentials)
+	run(CheckAccount, p.checkAccount)
+	run(CheckDryRunDomain, p.checkDryRunDomain)
+
+	if !report.Healthy {
+		var failed []string
+
+		for _, c := range report.Checks {
+			if c.Status == StatusFailed {
+				failed = append(failed, fmt.Sprintf("%s: %s", c.Name, c.Error))
+			}
+		}
+
+		return nil, ErrDryRunFailed.WithDetails(report).Wrap(errors.New(strings.Join(failed, "; ")))
+	}
+
+	return report, nil
+}
+
+// checkDirectory makes sure the ACME directory is reachable.
+func (p *producer) checkDirectory(ctx context.Context) error {
+	config := lego.NewConfig(&leUser{email: p.dryRunEmail})
+
+	_, err := fetchDirectory(ctx, config.HTTPClient, p.directoryURL)
+
+	return err
+}
+
+// checkDNSCredentials makes sure the DNS provider credentials can read the
+// hosted zone of the dry-run domain. It doesn't change any records, so it
+// doesn't wait for them to propagate.
+func (p *producer) checkDNSCredentials(ctx context.Context) error {
+	if p.dryRunDomain == "" {
+		return fmt.Errorf("%w: dry-run domain is not configured", errSkipped)
+	}
+
+	nameservers, err := p.resolvers()
+	if err != nil {
+		return err
+	}
+
+	zc, err := p.zones(ctx)
+	if err != nil {
+		return err
+	}
+
+	return checkZone(ctx, zc, nameservers, p.dryRunDomain)
+}
+
+// checkDryRunDomain makes sure the dry-run domain resolves, using the same
+// nameservers as pre-flight checks.
+func (p *producer) checkDryRunDomain(ctx context.Context) error {
+	if p.dryRunDomain == "" {
+		return fmt.Errorf("%w: dry-run domain is not configured", errSkipped)
+	}
+
+	nameservers, err := p.resolvers()
+	if err != nil {
+		return err
+	}
+
+	return resolveHost(ctx, nameservers, p.dryRunDomain)
+}
+
+// checkDNSProvider makes sure AWS credentials used by the Route 53 provider
+// can be resolved. Unlike checkDNSCredentials, it doesn't call Route 53, so
+// it is cheap enough to be used in readiness probes.
+func (p *producer) checkDNSProvider(ctx context.Context) error {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("can't load aws configuration: %w", err)
+	}
+
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return fmt.Errorf("can't retrieve aws credentials: %w", err)
+	}
+
+	return nil
+}
+
+// checkAccount registers an account with the dry-run email, the same way
+// every create does, since this producer doesn't keep an account store. The
+// CA rejecting the email, for example, is found before production requests.
+func (p *producer) checkAccount(ctx context.Context) (err error) {
+	if p.dryRunEmail == "" {
+		return fmt.Errorf("%w: dry-run email is not configured", errSkipped)
+	}
+
+	if _, err := mail.ParseAddress(p.dryRunEmail); err != nil {
+		return fmt.Errorf("invalid dry-run email '%s': %w", p.dryRunEmail, err)
+	}
+
+	_, span := tracer.Start(ctx, "acme.register")
+	defer func() { tracing.End(span, err) }()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("can't generate account key: %w", err)
+	}
+
+	config := lego.NewConfig(&leUser{email: p.dryRunEmail, key: key})
+	config.CADirURL = p.directoryURL
+
+	// lego doesn't accept a context, so its requests are bounded by the
+	// client timeout instead
+	if deadline, ok := ctx.Deadline(); ok {
+		config.HTTPClient.Timeout = time.Until(deadline)
+	}
+
+	client, err := lego.NewClient(config)
+	if err != nil {
+		return fmt.Errorf("can't create lets encrypt client: %w", err)
+	}
+
+	if _, err := client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true}); err != nil {
+		return fmt.Errorf("can't register account for %s: %w", p.dryRunEmail, err)
+	}
+
+	return nil
+}
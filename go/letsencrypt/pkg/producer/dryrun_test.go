@@ -0,0 +1,221 @@
+package producer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+// newFakeACME starts a CA that implements the parts of ACME used to register
+// accounts. It rejects contacts in the invalid.test domain, like Let's
+// Encrypt rejects contacts it can't deliver email to.
+func newFakeACME(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var nonces atomic.Int64
+
+	nonce := func(w http.ResponseWriter) {
+		w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", nonces.Add(1)))
+	}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/directory", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"newNonce":   srv.URL + "/nonce",
+			"newAccount": srv.URL + "/account",
+			"newOrder":   srv.URL + "/order",
+			"revokeCert": srv.URL + "/revoke",
+			"keyChange":  srv.URL + "/key-change",
+			"meta":       map[string]interface{}{"termsOfService": srv.URL + "/terms"},
+		})
+	})
+
+	mux.HandleFunc("/nonce", func(w http.ResponseWriter, _ *http.Request) {
+		nonce(w)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		nonce(w)
+
+		var jws struct {
+			Payload string `json:"payload"`
+		}
+
+		var account struct {
+			Contact              []string `json:"contact"`
+			TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+		if err == nil {
+			err = json.Unmarshal(payload, &account)
+		}
+
+		if err != nil || !account.TermsOfServiceAgreed || len(account.Contact) == 0 {
+			http.Error(w, "invalid account", http.StatusBadRequest)
+			return
+		}
+
+		if strings.HasSuffix(account.Contact[0], "@invalid.test") {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"type":"urn:ietf:params:acme:error:invalidContact","detail":"contact email has an invalid domain","status":400}`))
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", srv.URL+"/account/1")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "valid", "contact": account.Contact})
+	})
+
+	return srv
+}
+
+// hostedZones is a zoneChecker of a fixed set of hosted zones.
+type hostedZones []string
+
+func (z hostedZones) CheckZone(_ context.Context, zone string) error {
+	for _, hz := range z {
+		if hz == zone {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("public hosted zone %s not found", zone)
+}
+
+// unresponsiveZones is a zoneChecker of a DNS provider that never answers.
+type unresponsiveZones struct{}
+
+func (unresponsiveZones) CheckZone(ctx context.Context, _ string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func newDryRunProducer(t *testing.T, email string, zc zoneChecker) *producer {
+	t.Helper()
+
+	ns := newFakeNameserver(t, []string{
+		`example.test. 300 IN SOA ns.example.test. admin.example.test. 1 7200 3600 86400 300`,
+		`www.example.test. 300 IN A 192.0.2.1`,
+	})
+
+	pr, err := New(WithDryRunEmail(email), WithDryRunDomain("www.example.test"), WithNameservers(ns))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := pr.(*producer)
+	p.directoryURL = newFakeACME(t).URL + "/directory"
+	p.zones = func(context.Context) (zoneChecker, error) { return zc, nil }
+
+	return p
+}
+
+func TestDryRun(t *testing.T) {
+	p := newDryRunProducer(t, "admin@example.test", hostedZones{"example.test."})
+
+	report, err := p.dryRun(context.Background())
+	if err != nil {
+		t.Fatalf("dryRun() failed: %v", err)
+	}
+
+	want := []string{CheckACMEDirectory, CheckDNSCredentials, CheckAccount, CheckDryRunDomain}
+
+	if len(report.Checks) != len(want) {
+		t.Fatalf("report has %d checks, want %d", len(report.Checks), len(want))
+	}
+
+	for i, c := range report.Checks {
+		if c.Name != want[i] || c.Status != StatusOK {
+			t.Errorf("check %d is %+v, want %s to pass", i, c, want[i])
+		}
+	}
+}
+
+func TestDryRunFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		email  string
+		zones  zoneChecker
+		failed string
+	}{
+		{"account rejected by the CA", "admin@invalid.test", hostedZones{"example.test."}, CheckAccount},
+		{"zone not hosted", "admin@example.test", hostedZones{"other.test."}, CheckDNSCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newDryRunProducer(t, tt.email, tt.zones)
+
+			_, err := p.dryRun(context.Background())
+
+			var pErr *protocol.Error
+			if !errors.As(err, &pErr) || pErr.Code != ErrDryRunFailed.Code {
+				t.Fatalf("dryRun() = %v, want %v", err, ErrDryRunFailed)
+			}
+
+			report := pErr.Details.(*HealthReport)
+
+			for _, c := range report.Checks {
+				if failed := c.Status == StatusFailed; failed != (c.Name == tt.failed) {
+					t.Errorf("check %+v, want only %s to fail", c, tt.failed)
+				}
+			}
+		})
+	}
+}
+
+func TestDryRunSkipsUnconfiguredChecks(t *testing.T) {
+	p := newDryRunProducer(t, "", hostedZones{})
+	WithDryRunDomain("")(p)
+
+	report, err := p.dryRun(context.Background())
+	if err != nil {
+		t.Fatalf("dryRun() failed: %v", err)
+	}
+
+	for _, c := range report.Checks {
+		if want := StatusSkipped; c.Name != CheckACMEDirectory && c.Status != want {
+			t.Errorf("check %+v, want %s", c, want)
+		}
+	}
+}
+
+func TestCheckDNSCredentialsDeadline(t *testing.T) {
+	p := newDryRunProducer(t, "admin@example.test", unresponsiveZones{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if err := p.checkDNSCredentials(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("checkDNSCredentials() = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("checkDNSCredentials() returned after %s", d)
+	}
+}
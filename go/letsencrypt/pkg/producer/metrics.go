@@ -0,0 +1,138 @@
+package producer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ACME steps, as reported in metric labels.
+const (
+	stepRegister  = "register"
+	stepOrder     = "order"
+	stepChallenge = "challenge"
+	stepFinalize  = "finalize"
+)
+
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+)
+
+var acmeSteps = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "akeyless_producer",
+	Subsystem: "acme",
+	Name:      "step_duration_seconds",
+	Help:      "Duration of ACME steps by outcome.",
+	Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+}, []string{"step", "outcome"})
+
+func observeStep(step string, start time.Time, err error) {
+	observeStepUntil(step, start, time.Now(), err)
+}
+
+func observeStepUntil(step string, start, end time.Time, err error) {
+	outcome := outcomeSuccess
+	if err != nil {
+		outcome = outcomeFailure
+	}
+
+	acmeSteps.WithLabelValues(step, outcome).Observe(end.Sub(start).Seconds())
+}
+
+// stepTracker wraps a DNS provider to split a single lego Obtain call into
+// order, challenge and finalize steps: the order step ends when the first
+// challenge record is created, and the challenge step ends when the last
+// record is removed.
+//
+// It also reports DNS record changes as spans, children of the provided
+// context's span.
+type stepTracker struct {
+	challenge.Provider
+
+	ctx context.Context
+
+	mu          sync.Mutex
+	start       time.Time
+	challengeAt time.Time
+	finalizeAt  time.Time
+	presents    int
+	cleanups    int
+}
+
+func newStepTracker(ctx context.Context, p challenge.Provider) *stepTracker {
+	return &stepTracker{Provider: p, ctx: ctx, start: time.Now()}
+}
+
+// Present implements challenge.Provider.
+func (t *stepTracker) Present(domain, token, keyAuth string) error {
+	t.mu.Lock()
+	if t.presents == 0 {
+		t.challengeAt = time.Now()
+		trace.SpanFromContext(t.ctx).AddEvent("challenge started")
+	}
+	t.presents++
+	t.mu.Unlock()
+
+	_, span := tracer.Start(t.ctx, "dns.present", trace.WithAttributes(attribute.String("domain", domain)))
+	err := t.Provider.Present(domain, token, keyAuth)
+	tracing.End(span, err)
+
+	return err
+}
+
+// CleanUp implements challenge.Provider.
+func (t *stepTracker) CleanUp(domain, token, keyAuth string) error {
+	_, span := tracer.Start(t.ctx, "dns.cleanup", trace.WithAttributes(attribute.String("domain", domain)))
+	err := t.Provider.CleanUp(domain, token, keyAuth)
+	tracing.End(span, err)
+
+	t.mu.Lock()
+	t.cleanups++
+	if t.cleanups == t.presents {
+		t.finalizeAt = time.Now()
+		trace.SpanFromContext(t.ctx).AddEvent("challenge completed")
+	}
+	t.mu.Unlock()
+
+	return err
+}
+
+// Timeout implements challenge.ProviderTimeout, so that wrapping doesn't
+// change propagation timeouts of the underlying provider.
+func (t *stepTracker) Timeout() (timeout, interval time.Duration) {
+	if p, ok := t.Provider.(challenge.ProviderTimeout); ok {
+		return p.Timeout()
+	}
+
+	return dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval
+}
+
+// done records every step that completed, and the step that was in progress
+// when Obtain returned. lego removes challenge records before it reports
+// validation errors, so a failure after the records are removed is
+// attributed to the challenge step rather than to finalization.
+func (t *stepTracker) done(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case t.presents == 0:
+		observeStep(stepOrder, t.start, err)
+	case t.finalizeAt.IsZero() || err != nil:
+		observeStepUntil(stepOrder, t.start, t.challengeAt, nil)
+		observeStep(stepChallenge, t.challengeAt, err)
+	default:
+		observeStepUntil(stepOrder, t.start, t.challengeAt, nil)
+		observeStepUntil(stepChallenge, t.challengeAt, t.finalizeAt, nil)
+		observeStep(stepFinalize, t.finalizeAt, nil)
+	}
+}
@@ -0,0 +1,64 @@
+package producer
+
+import "net"
+
+// Option is a single configuration parameter used by this producer.
+type Option func(*producer)
+
+// WithDefaultEmail configures this producer to use the provided email to
+// access Let's Encrypt if the end user doesn't have an "email" sub-claim.
+func WithDefaultEmail(email string) Option {
+	return func(p *producer) {
+		p.defaultEmail = email
+	}
+}
+
+// WithDryRunEmail configures this webhook to use the provided email during
+// dry-run requests to Let's Encrypt service. Regular, "production" calls use
+// the email of an end user that initiated the operation (called
+// `get-dynamic-secret-value` command).
+func WithDryRunEmail(email string) Option {
+	return func(p *producer) {
+		p.dryRunEmail = email
+	}
+}
+
+// WithDryRunDomain configures this webhook to use the provided domain during
+// dry-run requests to Let's Encrypt service. Regular, "production" calls use
+// the domain provided alongside `get-dynamic-secret-value` operation.
+func WithDryRunDomain(domain string) Option {
+	return func(p *producer) {
+		p.dryRunDomain = domain
+	}
+}
+
+// WithPreflightChecks enables pre-flight checks that run before an order is
+// placed with Let's Encrypt: CAA records of every requested domain must allow
+// the CA to issue certificates, and Route 53 must host the authoritative zone
+// of every domain.
+func WithPreflightChecks(enabled bool) Option {
+	return func(p *producer) {
+		p.preflightChecks = enabled
+	}
+}
+
+// WithNameservers configures the recursive nameservers queried by pre-flight
+// checks and dry runs, as host or host:port. The nameservers in
+// /etc/resolv.conf are used by default.
+func WithNameservers(addrs ...string) Option {
+	return func(p *producer) {
+		p.nameservers = nil
+
+		for _, addr := range addrs {
+			if addr == "" {
+				continue
+			}
+
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				addr = net.JoinHostPort(addr, "53")
+			}
+
+			p.nameservers = append(p.nameservers, addr)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Names of the individual pre-flight checks, as reported in PreflightError.
+const (
+	CheckCAA     = "caa"
+	CheckDNSZone = "dns_zone"
+)
+
+const (
+	envHostedZoneID = "AWS_HOSTED_ZONE_ID"
+
+	// resolvConf lists the system nameservers, which are used unless
+	// nameservers are configured with WithNameservers.
+	resolvConf = "/etc/resolv.conf"
+
+	dnsTimeout = 5 * time.Second
+)
+
+// ErrPreflightFailed is returned when at least one pre-flight check fails.
+// Its details list every failed check.
+var ErrPreflightFailed = protocol.NewError(protocol.CodePreconditionFailed, http.StatusUnprocessableEntity, "pre-flight check failed")
+
+// PreflightError describes a single failed pre-flight check.
+type PreflightError struct {
+	Check  string `json:"check"`
+	Target string `json:"target"`
+	Reason string `json:"reason"`
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("%s check failed for '%s': %s", e.Check, e.Target, e.Reason)
+}
+
+// PreflightErrors lists every failed pre-flight check, not only the first one.
+type PreflightErrors []*PreflightError
+
+func (e PreflightErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns each individual failure.
+func (e PreflightErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// zoneChecker confirms that the DNS provider used to solve challenges manages
+// the provided (authoritative) zone.
+type zoneChecker interface {
+	CheckZone(ctx context.Context, zone string) error
+}
+
+// preflight runs every pre-flight check and reports all the failures at once.
+// None of the checks places an order or otherwise touches the CA, so failures
+// don't count against Let's Encrypt rate limits.
+func (p *producer) preflight(ctx context.Context, domains []string, dir *acme.Directory) (err error) {
+	ctx, span := tracer.Start(ctx, "preflight")
+	defer func() { tracing.End(span, err) }()
+
+	var errs PreflightErrors
+
+	nameservers, err := p.resolvers()
+	if err != nil {
+		errs = append(errs, &PreflightError{Check: CheckCAA, Target: "nameservers", Reason: err.Error()})
+	}
+
+	zc, err := p.zones(ctx)
+	if err != nil {
+		errs = append(errs, &PreflightError{Check: CheckDNSZone, Target: "route53", Reason: err.Error()})
+	}
+
+	if len(nameservers) == 0 {
+		return ErrPreflightFailed.WithDetails(errs).Wrap(errs)
+	}
+
+	for _, domain := range domains {
+		if err := checkCAA(ctx, nameservers, domain, dir.Meta.CaaIdentities); err != nil {
+			errs = append(errs, &PreflightError{Check: CheckCAA, Target: domain, Reason: err.Error()})
+		}
+
+		if zc == nil {
+			continue
+		}
+
+		if err := checkZone(ctx, zc, nameservers, domain); err != nil {
+			errs = append(errs, &PreflightError{Check: CheckDNSZone, Target: domain, Reason: err.Error()})
+		}
+	}
+
+	if len(errs) > 0 {
+		return ErrPreflightFailed.WithDetails(errs).Wrap(errs)
+	}
+
+	return nil
+}
+
+// resolvers returns the recursive nameservers that DNS checks query: the
+// ones configured with WithNameservers, or the ones in resolv.conf. Queries
+// are never sent to public resolvers, which can't see private zones.
+func (p *producer) resolvers() ([]string, error) {
+	if len(p.nameservers) > 0 {
+		return p.nameservers, nil
+	}
+
+	cfg, err := dns.ClientConfigFromFile(resolvConf)
+	if err != nil {
+		return nil, fmt.Errorf("can't read nameservers: %w", err)
+	}
+
+	if len(cfg.Servers) == 0 {
+		return nil, fmt.Errorf("%s doesn't list any nameservers", resolvConf)
+	}
+
+	servers := make([]string, 0, len(cfg.Servers))
+	for _, s := range cfg.Servers {
+		servers = append(servers, net.JoinHostPort(s, cfg.Port))
+	}
+
+	return servers, nil
+}
+
+// checkZone makes sure the DNS provider manages the authoritative zone of the
+// domain.
+func checkZone(ctx context.Context, zc zoneChecker, nameservers []string, domain string) error {
+	_, span := tracer.Start(ctx, "dns.find_zone", trace.WithAttributes(attribute.String("domain", domain)))
+	zone, err := findZone(ctx, nameservers, dns01.ToFqdn(strings.TrimPrefix(domain, "*.")))
+	tracing.End(span, err)
+
+	if err != nil {
+		return err
+	}
+
+	return zc.CheckZone(ctx, zone)
+}
+
+// newRoute53ZoneChecker creates a zoneChecker of the Route 53 hosted zones
+// that challenges are solved in.
+func newRoute53ZoneChecker(ctx context.Context) (zoneChecker, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't load aws configuration: %w", err)
+	}
+
+	return &route53ZoneChecker{
+		client:       route53.NewFromConfig(cfg),
+		hostedZoneID: os.Getenv(envHostedZoneID),
+	}, nil
+}
+
+// checkCAA makes sure that CAA records of the domain (or of its closest
+// ancestor that has any, see RFC 8659) allow one of the provided CA
+// identities to issue certificates. If the CA doesn't advertise its
+// identities, only the presence of an explicit "deny all" record is checked.
+func checkCAA(ctx context.Context, nameservers []string, domain string, caIdentities []string) (err error) {
+	ctx, span := tracer.Start(ctx, "dns.caa", trace.WithAttributes(attribute.String("domain", domain)))
+	defer func() { tracing.End(span, err) }()
+
+	wildcard := strings.HasPrefix(domain, "*.")
+	name := dns01.ToFqdn(strings.TrimPrefix(domain, "*."))
+
+	records, err := lookupCAA(ctx, nameservers, name)
+	if err != nil {
+		return err
+	}
+
+	var issue, issueWild []string
+
+	for _, r := range records {
+		switch strings.ToLower(r.Tag) {
+		case "issue":
+			issue = append(issue, r.Value)
+		case "issuewild":
+			issueWild = append(issueWild, r.Value)
+		}
+	}
+
+	allowed := issue
+	if wildcard && len(issueWild) > 0 {
+		allowed = issueWild
+	}
+
+	// no issue properties means any CA may issue
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	for _, value := range allowed {
+		issuer := strings.TrimSpace(strings.SplitN(value, ";", 2)[0])
+		if issuer == "" {
+			continue
+		}
+
+		if len(caIdentities) == 0 {
+			return nil
+		}
+
+		for _, id := range caIdentities {
+			if strings.EqualFold(issuer, id) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("caa records don't allow issuance by %s", strings.Join(caIdentities, ", "))
+}
+
+// lookupCAA returns the relevant CAA record set for the provided FQDN by
+// climbing the DNS tree until a non-empty set is found. Aliases are followed
+// by the recursive nameserver, which returns the records of their target.
+func lookupCAA(ctx context.Context, nameservers []string, fqdn string) ([]*dns.CAA, error) {
+	labels := dns.SplitDomainName(fqdn)
+
+	for i := range labels {
+		name := dns.Fqdn(strings.Join(labels[i:], "."))
+
+		in, err := exchange(ctx, nameservers, name, dns.TypeCAA)
+		if err != nil {
+			return nil, err
+		}
+
+		var records []*dns.CAA
+
+		for _, rr := range in.Answer {
+			if caa, ok := rr.(*dns.CAA); ok {
+				records = append(records, caa)
+			}
+		}
+
+		if len(records) > 0 {
+			return records, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// findZone returns the zone that is authoritative for the FQDN: the closest
+// ancestor that has an SOA record.
+func findZone(ctx context.Context, nameservers []string, fqdn string) (string, error) {
+	labels := dns.SplitDomainName(fqdn)
+
+	for i := range labels {
+		name := dns.Fqdn(strings.Join(labels[i:], "."))
+
+		in, err := exchange(ctx, nameservers, name, dns.TypeSOA)
+		if err != nil {
+			return "", err
+		}
+
+		// the SOA of an alias target isn't the zone of the alias
+		for _, rr := range in.Answer {
+			if soa, ok := rr.(*dns.SOA); ok && strings.EqualFold(soa.Hdr.Name, name) {
+				return name, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("can't find the zone of %s", fqdn)
+}
+
+// resolveHost makes sure the provided name has an A or AAAA record.
+func resolveHost(ctx context.Context, nameservers []string, host string) error {
+	name := dns.Fqdn(host)
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		in, err := exchange(ctx, nameservers, name, qtype)
+		if err != nil {
+			return err
+		}
+
+		for _, rr := range in.Answer {
+			if rr.Header().Rrtype == qtype {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%s doesn't have any A or AAAA records", host)
+}
+
+// exchange sends the query to each nameserver in turn, until one of them
+// answers. Answers other than success and NXDOMAIN, such as SERVFAIL, are
+// failures, since the records can't be trusted to be missing. The query is
+// abandoned as soon as the context is done.
+func exchange(ctx context.Context, nameservers []string, name string, qtype uint16) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(name, qtype)
+
+	c := &dns.Client{Timeout: dnsTimeout}
+
+	var err error
+
+	for _, ns := range nameservers {
+		var in *dns.Msg
+
+		in, err = exchangeWith(ctx, c, msg, ns)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s lookup for %s failed: %w", dns.TypeToString[qtype], name, ctx.Err())
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if in.Rcode != dns.RcodeSuccess && in.Rcode != dns.RcodeNameError {
+			err = fmt.Errorf("%s answered %s", ns, dns.RcodeToString[in.Rcode])
+			continue
+		}
+
+		return in, nil
+	}
+
+	return nil, fmt.Errorf("%s lookup for %s failed: %w", dns.TypeToString[qtype], name, err)
+}
+
+// exchangeWith sends a single query to the nameserver. Unlike
+// dns.Client.ExchangeContext, it also stops waiting when the context is
+// canceled, not only when its deadline passes.
+func exchangeWith(ctx context.Context, c *dns.Client, msg *dns.Msg, nameserver string) (*dns.Msg, error) {
+	conn, err := c.DialContext(ctx, nameserver)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	in, _, err := c.ExchangeWithConnContext(ctx, msg, conn)
+
+	return in, err
+}
+
+type route53ZoneChecker struct {
+	client       *route53.Client
+	hostedZoneID string
+}
+
+// CheckZone looks for a public hosted zone with the provided name. If a
+// hosted zone ID is configured explicitly, only its existence is checked.
+func (c *route53ZoneChecker) CheckZone(ctx context.Context, zone string) (err error) {
+	ctx, span := tracer.Start(ctx, "dns.check_zone", trace.WithAttributes(attribute.String("zone", zone)))
+	defer func() { tracing.End(span, err) }()
+
+	if c.hostedZoneID != "" {
+		if _, err := c.client.GetHostedZone(ctx, &route53.GetHostedZoneInput{Id: aws.String(c.hostedZoneID)}); err != nil {
+			return fmt.Errorf("can't read hosted zone %s: %w", c.hostedZoneID, err)
+		}
+
+		return nil
+	}
+
+	res, err := c.client.ListHostedZonesByName(ctx, &route53.ListHostedZonesByNameInput{
+		DNSName: aws.String(dns01.UnFqdn(zone)),
+	})
+	if err != nil {
+		return fmt.Errorf("can't list hosted zones: %w", err)
+	}
+
+	for _, hz := range res.HostedZones {
+		if !hz.Config.PrivateZone && aws.ToString(hz.Name) == zone {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("public hosted zone %s not found", zone)
+}
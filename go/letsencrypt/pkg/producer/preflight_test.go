@@ -0,0 +1,196 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// newFakeNameserver starts a recursive nameserver answering from the
+// provided records. Aliases are followed, like a recursive nameserver would,
+// and queries for names in servfail fail.
+func newFakeNameserver(t *testing.T, records []string, servfail ...string) string {
+	t.Helper()
+
+	var rrs []dns.RR
+
+	for _, r := range records {
+		rr, err := dns.NewRR(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rrs = append(rrs, rr)
+	}
+
+	handler := func(w dns.ResponseWriter, req *dns.Msg) {
+		res := new(dns.Msg)
+		res.SetReply(req)
+
+		q := req.Question[0]
+
+		for _, name := range servfail {
+			if dns.Fqdn(name) == q.Name {
+				res.Rcode = dns.RcodeServerFailure
+				_ = w.WriteMsg(res)
+
+				return
+			}
+		}
+
+		name := q.Name
+
+		for _, rr := range rrs {
+			if cname, ok := rr.(*dns.CNAME); ok && rr.Header().Name == name {
+				res.Answer = append(res.Answer, rr)
+				name = cname.Target
+			}
+		}
+
+		for _, rr := range rrs {
+			if rr.Header().Name == name && rr.Header().Rrtype == q.Qtype {
+				res.Answer = append(res.Answer, rr)
+			}
+		}
+
+		_ = w.WriteMsg(res)
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(handler), NotifyStartedFunc: func() { close(started) }}
+
+	go func() { _ = srv.ActivateAndServe() }()
+
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	<-started
+
+	return pc.LocalAddr().String()
+}
+
+func TestCheckCAA(t *testing.T) {
+	ns := newFakeNameserver(t, []string{
+		`allowed.test. 300 IN CAA 0 issue "letsencrypt.org"`,
+		`forbidden.test. 300 IN CAA 0 issue "pki.goog; cansignhttpexchanges=yes"`,
+		`wildcard.test. 300 IN CAA 0 issue "letsencrypt.org"`,
+		`wildcard.test. 300 IN CAA 0 issuewild ";"`,
+		`alias.allowed.test. 300 IN CNAME forbidden.test.`,
+		`www.forbidden.test. 300 IN CNAME target.allowed.test.`,
+	}, "broken.test")
+
+	tests := []struct {
+		name    string
+		domain  string
+		wantErr string
+	}{
+		{"issuer allowed", "allowed.test", ""},
+		{"issuer forbidden", "forbidden.test", "don't allow issuance"},
+		{"no records", "unrestricted.test", ""},
+		{"parent allowed", "www.allowed.test", ""},
+		{"parent forbidden", "a.b.forbidden.test", "don't allow issuance"},
+		{"alias target forbidden", "alias.allowed.test", "don't allow issuance"},
+		// the tree of the alias is climbed, not the one of its target
+		{"alias parent forbidden", "www.forbidden.test", "don't allow issuance"},
+		{"wildcard forbidden", "*.wildcard.test", "don't allow issuance"},
+		{"wildcard allowed by issue", "*.allowed.test", ""},
+		{"servfail", "www.broken.test", "SERVFAIL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCAA(context.Background(), []string{ns}, tt.domain, []string{"letsencrypt.org"})
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("checkCAA() failed: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("checkCAA() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckCAANextNameserver(t *testing.T) {
+	broken := newFakeNameserver(t, nil, "forbidden.test")
+	ns := newFakeNameserver(t, []string{`forbidden.test. 300 IN CAA 0 issue "pki.goog"`})
+
+	err := checkCAA(context.Background(), []string{broken, ns}, "forbidden.test", []string{"letsencrypt.org"})
+	if err == nil || !strings.Contains(err.Error(), "don't allow issuance") {
+		t.Errorf("checkCAA() = %v, want the answer of the second nameserver", err)
+	}
+}
+
+func TestCheckCAACanceled(t *testing.T) {
+	// nothing reads from this socket, so queries are never answered
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = pc.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+
+	err = checkCAA(ctx, []string{pc.LocalAddr().String()}, "example.test", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("checkCAA() = %v, want %v", err, context.Canceled)
+	}
+
+	if d := time.Since(start); d >= dnsTimeout {
+		t.Errorf("checkCAA() returned after %s, not when the context was canceled", d)
+	}
+}
+
+func TestCheckDryRunDomain(t *testing.T) {
+	ns := newFakeNameserver(t, []string{
+		`ipv4.test. 300 IN A 192.0.2.1`,
+		`ipv6.test. 300 IN AAAA 2001:db8::1`,
+	})
+
+	tests := []struct {
+		domain  string
+		wantErr bool
+	}{
+		{"ipv4.test", false},
+		{"ipv6.test", false},
+		{"missing.test", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			p := &producer{}
+			WithNameservers(ns)(p)
+			WithDryRunDomain(tt.domain)(p)
+
+			if err := p.checkDryRunDomain(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("checkDryRunDomain() = %v, want error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithNameservers(t *testing.T) {
+	p := &producer{}
+	WithNameservers("10.0.0.2", "", "[fd00::53]:5353")(p)
+
+	got, err := p.resolvers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"10.0.0.2:53", "[fd00::53]:5353"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("resolvers() = %v, want %v", got, want)
+	}
+}
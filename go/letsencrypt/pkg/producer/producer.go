@@ -0,0 +1,268 @@
+// Package producer implements Let's Encrypt certificate automation using
+// Akeyless Dynamic Secrets.
+package producer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/go-acme/lego/v4/providers/dns/route53"
+	"github.com/go-acme/lego/v4/registration"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/akeylesslabs/custom-producer/go/letsencrypt/pkg/producer")
+
+// ErrMissingSubClaim is returned when the original user doesn't have an
+// "email" sub-claim in their access credentials.
+var ErrMissingSubClaim = protocol.NewError("missing_sub_claim", http.StatusBadRequest, "email sub-claim is required")
+
+// ErrInvalidInput is returned when the input provided alongside
+// `get-dynamic-secret-value` operation is invalid or isn't allowed by the CA.
+var ErrInvalidInput = protocol.NewError(protocol.CodeInvalidInput, http.StatusBadRequest, "invalid input")
+
+// ErrObtainFailed is returned when Let's Encrypt didn't issue a certificate.
+// The underlying error is only logged since it may include internal details.
+var ErrObtainFailed = &protocol.Error{
+	Code:      protocol.CodeUpstreamError,
+	Status:    http.StatusBadGateway,
+	Message:   "failed to obtain a new certificate",
+	Retryable: true,
+}
+
+// Producer is an implementation of Akeyless Custom Producer.
+type Producer interface {
+	protocol.Producer
+
+	// ReadinessChecks returns named checks that must pass for the producer
+	// to be considered ready to serve requests.
+	ReadinessChecks() map[string]func(context.Context) error
+}
+
+// New creates a new Producer with the provided options.
+func New(opts ...Option) (Producer, error) {
+	p := &producer{
+		directoryURL: lego.LEDirectoryProduction,
+		zones:        newRoute53ZoneChecker,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p, nil
+}
+
+type producer struct {
+	defaultEmail    string
+	dryRunEmail     string
+	dryRunDomain    string
+	preflightChecks bool
+	nameservers     []string
+
+	// directoryURL and zones are replaced in tests
+	directoryURL string
+	zones        func(context.Context) (zoneChecker, error)
+}
+
+func (p *producer) Create(ctx context.Context, r *protocol.CreateRequest) (*protocol.CreateResponse, error) {
+	// dry run mode makes sure that the producer configuration is valid
+	// without actually obtaining a certificate
+	if r.ClientInfo.AccessID == protocol.DryRunAccessID {
+		report, err := p.dryRun(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return &protocol.CreateResponse{Response: report}, nil
+	}
+
+	var email string
+
+	switch emailClaims := r.ClientInfo.SubClaims["email"]; len(emailClaims) {
+	case 0:
+		// if no email sub-claim is set, try using the default one
+		if p.defaultEmail == "" {
+			return nil, ErrMissingSubClaim
+		}
+
+		email = p.defaultEmail
+	default:
+		email = emailClaims[0]
+	}
+
+	var inp Input
+	if err := r.Input.Decode(&inp); err != nil {
+		return nil, ErrInvalidInput.Wrap(err)
+	}
+
+	certOut, err := p.obtainCertificate(ctx, email, inp)
+	if err != nil {
+		var pErr *protocol.Error
+		if errors.As(err, &pErr) {
+			return nil, err
+		}
+
+		return nil, ErrObtainFailed.Wrap(err)
+	}
+
+	return &protocol.CreateResponse{
+		ID:       "",
+		Response: certOut,
+	}, nil
+}
+
+func (p *producer) Revoke(_ context.Context, r *protocol.RevokeRequest) (*protocol.RevokeResponse, error) {
+	// This producer doesn't allow to revoke temporary credentials.
+	// Here we only return the same user ids that we received even though we do
+	// nothing with them.
+
+	return &protocol.RevokeResponse{
+		Revoked: r.IDs,
+	}, nil
+}
+
+// Describe implements protocol.Describer. This producer doesn't use its
+// payload.
+func (p *producer) Describe() protocol.Description {
+	return protocol.Description{Input: Input{}}
+}
+
+func (p *producer) ReadinessChecks() map[string]func(context.Context) error {
+	return map[string]func(context.Context) error{
+		CheckACMEDirectory: p.checkDirectory,
+		CheckDNSProvider:   p.checkDNSProvider,
+	}
+}
+
+// obtainCertificate requests a new certificate from Let's Encrypt and attempts
+// to solve the challenge to prove our identity.
+//
+// Currently, only DNS challenge can be solved, and only Route53 (AWS) is
+// supported. To support other cloud environments, this function and its input
+// should be modified.
+//
+// The environment that runs this producer must be able to authenticate
+// seamlessly with the cloud provider and have sufficient permissions to manage
+// DNS records.
+//
+// If pre-flight checks are enabled, they run after the input is validated and
+// before any order is placed.
+func (p *producer) obtainCertificate(ctx context.Context, email string, inp Input) (*certOutput, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't generate private key: %w", err)
+	}
+
+	user := leUser{email: email, key: privateKey}
+	config := lego.NewConfig(&user)
+	config.CADirURL = p.directoryURL
+
+	if inp.UseStaging {
+		config.CADirURL = lego.LEDirectoryStaging
+	}
+
+	if inp.KeyType != "" {
+		keyType, ok := keyTypes[inp.KeyType]
+		if !ok {
+			return nil, ErrInvalidInput.WithMessage("unknown key type '%s'", inp.KeyType)
+		}
+
+		config.Certificate.KeyType = keyType
+	}
+
+	dir, err := fetchDirectory(ctx, config.HTTPClient, config.CADirURL)
+	if err != nil {
+		return nil, fmt.Errorf("can't read acme directory: %w", err)
+	}
+
+	if err := validateInput(inp, dir); err != nil {
+		return nil, err
+	}
+
+	var domainList []string
+
+	for _, domain := range strings.Split(inp.Domain, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			domainList = append(domainList, domain)
+		}
+	}
+
+	if p.preflightChecks {
+		if err := p.preflight(ctx, domainList, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	_, span := tracer.Start(ctx, "acme.new_client")
+	client, err := lego.NewClient(config)
+	tracing.End(span, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("can't crate lets encrypt client: %w", err)
+	}
+
+	_, span = tracer.Start(ctx, "acme.register")
+	registerStart := time.Now()
+	user.registration, err = client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
+	observeStep(stepRegister, registerStart, err)
+	tracing.End(span, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("can't obtain lets encrypt registration for %s: %w", email, err)
+	}
+
+	r53, err := route53.NewDNSProvider()
+	if err != nil {
+		return nil, fmt.Errorf("can't create a new route53 dns provider: %w", err)
+	}
+
+	// DNS record changes are reported as children of the obtain span
+	obtainCtx, span := tracer.Start(ctx, "acme.obtain", trace.WithAttributes(
+		attribute.StringSlice("domains", domainList),
+		attribute.String("profile", inp.Profile),
+	))
+	tracker := newStepTracker(obtainCtx, r53)
+
+	if err := client.Challenge.SetDNS01Provider(tracker); err != nil {
+		tracing.End(span, err)
+		return nil, fmt.Errorf("can't setup a new dns challenge using route53 provider: %w", err)
+	}
+
+	out, err := client.Certificate.Obtain(certificate.ObtainRequest{
+		Domains:    domainList,
+		MustStaple: inp.MustStaple,
+		Profile:    inp.Profile,
+	})
+	tracker.done(err)
+	tracing.End(span, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("can't obtain certificates for domain %v: %w", inp.Domain, err)
+	}
+
+	// we use an internal type instead of reusing lego type since we want to
+	// include every field in json while lego type skips some of them
+	return &certOutput{
+		Domain:            out.Domain,
+		CertURL:           out.CertURL,
+		CertStableURL:     out.CertStableURL,
+		PrivateKey:        out.PrivateKey,
+		Certificate:       out.Certificate,
+		IssuerCertificate: out.IssuerCertificate,
+		CSR:               out.CSR,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package producer
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/registration"
+)
+
+// Input includes variables specific to Let's Encrypt producer. The input
+// should be provided with `get-dynamic-secret-value` operation.
+//
+// Domain is a comma-separated list of DNS names. MustStaple and Profile are
+// optional and are only honored if the CA supports them. KeyType selects the
+// type of the certificate private key, RSA2048 by default.
+type Input struct {
+	UseStaging bool   `json:"use_staging" description:"Use Let's Encrypt staging environment"`
+	Domain     string `json:"domain" validate:"required" pattern:"([*][.])?([A-Za-z0-9-]+[.])+[A-Za-z0-9-]+( *, *([*][.])?([A-Za-z0-9-]+[.])+[A-Za-z0-9-]+)*" description:"Comma separated list of domains. The first one is the common name"`
+	MustStaple bool   `json:"must_staple,omitempty" description:"Request the OCSP Must-Staple extension"`
+	Profile    string `json:"profile,omitempty" validate:"max=64" description:"ACME certificate profile advertised by the CA, for example, shortlived"`
+	KeyType    string `json:"key_type,omitempty" validate:"enum=EC256|EC384|RSA2048|RSA3072|RSA4096" description:"Type of the certificate private key"`
+}
+
+// keyTypes maps key types accepted in the input to lego key types.
+var keyTypes = map[string]certcrypto.KeyType{
+	"EC256":   certcrypto.EC256,
+	"EC384":   certcrypto.EC384,
+	"RSA2048": certcrypto.RSA2048,
+	"RSA3072": certcrypto.RSA3072,
+	"RSA4096": certcrypto.RSA4096,
+}
+
+type leUser struct {
+	email        string
+	registration *registration.Resource
+	key          crypto.PrivateKey
+}
+
+func (u *leUser) GetEmail() string {
+	return u.email
+}
+
+func (u leUser) GetRegistration() *registration.Resource {
+	return u.registration
+}
+
+func (u *leUser) GetPrivateKey() crypto.PrivateKey {
+	return u.key
+}
+
+type certOutput struct {
+	Domain            string `json:"domain"`
+	CertURL           string `json:"cert_url"`
+	CertStableURL     string `json:"cert_stable_url"`
+	PrivateKey        []byte `json:"private_key"`
+	Certificate       []byte `json:"certificate"`
+	IssuerCertificate []byte `json:"issuer_certificate"`
+	CSR               []byte `json:"csr"`
+}
+
+// SerialNumber returns the serial number of the issued certificate in
+// hexadecimal, to be stored in audit records.
+func (o *certOutput) SerialNumber() string {
+	block, _ := pem.Decode(o.Certificate)
+	if block == nil {
+		return ""
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return ""
+	}
+
+	return cert.SerialNumber.Text(16)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/config"
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/ratelimit"
+	"github.com/akeylesslabs/custom-producer/go/pkg/server"
+)
+
+// Config describes the server and every producer it hosts.
+type Config struct {
+	Server  server.Config `yaml:"server" toml:"server" json:"server"`
+	Log     Log           `yaml:"log" toml:"log" json:"log"`
+	Tracing Tracing       `yaml:"tracing" toml:"tracing" json:"tracing"`
+	// Sweeper configures revocation of credentials that Akeyless didn't
+	// revoke. It is enabled for producers with a sweeper TTL.
+	Sweeper   server.SweeperConfig `yaml:"sweeper" toml:"sweeper" json:"sweeper"`
+	Producers []Producer           `yaml:"producers" toml:"producers" json:"producers"`
+
+	// PayloadKeys are base64 encoded AES-256 keys that decrypt payloads of
+	// every producer. If set, plain payloads are rejected.
+	PayloadKeys []string `yaml:"payload_keys" toml:"payload_keys" json:"payload_keys" env:"PAYLOAD_KEYS"`
+}
+
+// Log configures logging.
+type Log struct {
+	Level string `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL"`
+}
+
+// Tracing configures OpenTelemetry tracing.
+type Tracing struct {
+	Exporter string `yaml:"exporter" toml:"exporter" json:"exporter" env:"OTEL_TRACES_EXPORTER"`
+}
+
+// Producer is a single hosted producer.
+type Producer struct {
+	// Name identifies the producer in metrics labels and logs.
+	Name string `yaml:"name" toml:"name" json:"name"`
+	// Type is one of the supported producer implementations.
+	Type string `yaml:"type" toml:"type" json:"type"`
+	// Prefix serves the producer under a path, for example, "/letsencrypt".
+	Prefix string `yaml:"prefix" toml:"prefix" json:"prefix"`
+	// AccessID and ItemName are the auth policy of the producer. Producers
+	// with an item name are also selected by the item making the request.
+	AccessID   string     `yaml:"access_id" toml:"access_id" json:"access_id"`
+	ItemName   string     `yaml:"item_name" toml:"item_name" json:"item_name"`
+	RateLimits RateLimits `yaml:"rate_limits" toml:"rate_limits" json:"rate_limits"`
+	// SweeperTTL is the longest TTL of the producer item. Credentials that
+	// weren't revoked by then are revoked by the sweeper.
+	SweeperTTL config.Duration `yaml:"sweeper_ttl" toml:"sweeper_ttl" json:"sweeper_ttl"`
+	// Settings are specific to the producer type.
+	Settings map[string]string `yaml:"settings" toml:"settings" json:"settings"`
+}
+
+// RateLimits configures limits of create requests of a single producer.
+type RateLimits struct {
+	AccessID      string `yaml:"access_id" toml:"access_id" json:"access_id"`
+	ItemName      string `yaml:"item_name" toml:"item_name" json:"item_name"`
+	SubClaim      string `yaml:"sub_claim" toml:"sub_claim" json:"sub_claim"`
+	SubClaimName  string `yaml:"sub_claim_name" toml:"sub_claim_name" json:"sub_claim_name"`
+	MaxConcurrent int    `yaml:"max_concurrent" toml:"max_concurrent" json:"max_concurrent"`
+}
+
+// Validate implements config.Validator.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if len(c.Producers) == 0 {
+		errs = append(errs, errors.New("at least one producer is required"))
+	}
+
+	for i, p := range c.Producers {
+		if _, ok := factories[p.Type]; !ok {
+			errs = append(errs, fmt.Errorf("producers[%d]: unknown type '%s'", i, p.Type))
+		}
+
+		if p.AccessID == "" {
+			errs = append(errs, fmt.Errorf("producers[%d]: access_id is required", i))
+		}
+
+		if p.Prefix == "" && p.ItemName == "" {
+			errs = append(errs, fmt.Errorf("producers[%d]: prefix or item_name is required", i))
+		}
+
+		rates := [][2]string{
+			{"access_id", p.RateLimits.AccessID},
+			{"item_name", p.RateLimits.ItemName},
+			{"sub_claim", p.RateLimits.SubClaim},
+		}
+
+		for _, rate := range rates {
+			if rate[1] == "" {
+				continue
+			}
+
+			if _, err := ratelimit.ParseRate(rate[1]); err != nil {
+				errs = append(errs, fmt.Errorf("producers[%d].rate_limits.%s: %w", i, rate[0], err))
+			}
+		}
+
+		if p.RateLimits.SubClaim != "" && p.RateLimits.SubClaimName == "" {
+			errs = append(errs, fmt.Errorf("producers[%d].rate_limits.sub_claim_name is required with sub_claim", i))
+		}
+
+		if p.SweeperTTL < 0 {
+			errs = append(errs, fmt.Errorf("producers[%d].sweeper_ttl must not be negative", i))
+		}
+	}
+
+	if c.Sweeper.Interval < 0 || c.Sweeper.Grace < 0 {
+		errs = append(errs, errors.New("sweeper.interval and sweeper.grace must not be negative"))
+	}
+
+	for i, key := range c.PayloadKeys {
+		if _, err := payload.ParseKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("payload_keys[%d]: %w", i, err))
+		}
+	}
+
+	// the store keeps payloads as received, which must not be plain text
+	if c.Sweeper.StoreFile != "" && len(c.PayloadKeys) == 0 {
+		errs = append(errs, errors.New("sweeper.store_file requires payload_keys"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,225 @@
+// Command cmd serves several Akeyless Custom Producers from a single
+// process, as described by a YAML, TOML or JSON config file.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	echo "github.com/akeylesslabs/custom-producer/go/echoserver/pkg/producer"
+	keycloak "github.com/akeylesslabs/custom-producer/go/keycloak/pkg/producer"
+	"github.com/akeylesslabs/custom-producer/go/letsencrypt/pkg/producer"
+	"github.com/akeylesslabs/custom-producer/go/pkg/config"
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/ratelimit"
+	"github.com/akeylesslabs/custom-producer/go/pkg/server"
+	"github.com/akeylesslabs/custom-producer/go/pkg/sweeper"
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"github.com/akeylesslabs/custom-producer/go/pkg/webhook"
+	splunk "github.com/akeylesslabs/custom-producer/go/splunk/pkg/producer"
+)
+
+// factories create producers of every supported type from their settings.
+var factories = map[string]func(settings map[string]string) (protocol.Producer, error){
+	"letsencrypt": func(s map[string]string) (protocol.Producer, error) {
+		return producer.New(
+			producer.WithDefaultEmail(s["email"]),
+			producer.WithDryRunEmail(s["dry_run_email"]),
+			producer.WithDryRunDomain(s["dry_run_domain"]),
+			producer.WithPreflightChecks(s["preflight_checks"] == "true"),
+		)
+	},
+	"echoserver": func(map[string]string) (protocol.Producer, error) {
+		return &echo.Producer{}, nil
+	},
+	"splunk": func(s map[string]string) (protocol.Producer, error) {
+		opts := []splunk.Option{splunk.WithAllowedRoles(list(s["allowed_roles"])...)}
+
+		if roles := list(s["default_roles"]); len(roles) > 0 {
+			opts = append(opts, splunk.WithDefaultRoles(roles...))
+		}
+
+		if prefix := s["username_prefix"]; prefix != "" {
+			opts = append(opts, splunk.WithUsernamePrefix(prefix))
+		}
+
+		return splunk.New(opts...)
+	},
+	"keycloak": func(s map[string]string) (protocol.Producer, error) {
+		opts := []keycloak.Option{
+			keycloak.WithDefaultURL(s["url"]),
+			keycloak.WithPasswordSymbols(s["password_symbols"] == "true"),
+		}
+
+		if v := s["password_length"]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("can't parse password_length: %w", err)
+			}
+
+			opts = append(opts, keycloak.WithPasswordLength(n))
+		}
+
+		return keycloak.New(opts...)
+	},
+}
+
+func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path of a YAML, TOML or JSON config file")
+	flag.Parse()
+
+	var cfg Config
+	if err := config.Load(*configPath, &cfg); err != nil {
+		fatal(err)
+	}
+
+	slog.SetDefault(logging.New(os.Stderr, logging.ParseLevel(cfg.Log.Level)))
+
+	shutdownTracing, err := tracing.Setup(
+		context.Background(),
+		tracing.WithExporter(cfg.Tracing.Exporter),
+		tracing.WithServiceName("akeyless-producers"),
+	)
+	if err != nil {
+		fatal(err)
+	}
+
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
+
+	sw, err := newSweeper(&cfg)
+	if err != nil {
+		fatal(err)
+	}
+
+	var keys [][]byte
+
+	// keys were validated when the config was loaded
+	for _, k := range cfg.PayloadKeys {
+		key, _ := payload.ParseKey(k)
+		keys = append(keys, key)
+	}
+
+	var routes []webhook.Route
+
+	for _, pc := range cfg.Producers {
+		p, err := factories[pc.Type](pc.Settings)
+		if err != nil {
+			fatal(err)
+		}
+
+		opts := hookOptions(pc)
+		if len(keys) > 0 {
+			opts = append(opts, webhook.WithPayloadKeys(keys...))
+		}
+		if pc.SweeperTTL > 0 {
+			opts = append(opts, webhook.WithSweeper(sw, time.Duration(pc.SweeperTTL)))
+		}
+
+		routes = append(routes, webhook.Route{
+			Name:     pc.Name,
+			Prefix:   pc.Prefix,
+			Producer: p,
+			Options:  opts,
+		})
+	}
+
+	h, err := webhook.NewMux(routes...)
+	if err != nil {
+		fatal(err)
+	}
+
+	srv, err := server.New(h, cfg.Server.Options()...)
+	if err != nil {
+		fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if sw != nil {
+		go sw.Run(ctx)
+	}
+
+	if err := srv.Run(ctx); err != nil {
+		fatal(err)
+	}
+}
+
+func hookOptions(pc Producer) []webhook.Option {
+	opts := []webhook.Option{
+		webhook.WithAllowedAccessID(pc.AccessID),
+		webhook.WithAllowedItemName(pc.ItemName),
+	}
+
+	rules := []struct {
+		name string
+		rate string
+		keys webhook.KeyFunc
+	}{
+		{"access_id", pc.RateLimits.AccessID, webhook.ByAccessID()},
+		{"item_name", pc.RateLimits.ItemName, webhook.ByItemName(pc.ItemName)},
+		{"sub_claim", pc.RateLimits.SubClaim, webhook.BySubClaim(pc.RateLimits.SubClaimName)},
+	}
+
+	for _, rule := range rules {
+		if rule.rate == "" {
+			continue
+		}
+
+		// rates were validated when the config was loaded
+		rate, _ := ratelimit.ParseRate(rule.rate)
+		opts = append(opts, webhook.WithRateLimit(rule.name, ratelimit.NewMemory(rate), rule.keys))
+	}
+
+	if n := pc.RateLimits.MaxConcurrent; n > 0 {
+		opts = append(opts, webhook.WithConcurrencyLimit(ratelimit.NewSemaphore(n)))
+	}
+
+	return opts
+}
+
+// newSweeper creates a sweeper if any producer has a sweeper TTL.
+func newSweeper(cfg *Config) (*sweeper.Sweeper, error) {
+	enabled := false
+	for _, pc := range cfg.Producers {
+		enabled = enabled || pc.SweeperTTL > 0
+	}
+
+	if !enabled {
+		return nil, nil
+	}
+
+	return server.NewSweeper(cfg.Sweeper)
+}
+
+// list splits a comma separated setting.
+func list(s string) []string {
+	var values []string
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+func fatal(err error) {
+	slog.Error("fatal error", "error", err)
+	os.Exit(1)
+}
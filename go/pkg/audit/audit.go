@@ -0,0 +1,194 @@
+// Package audit records every credential issued or revoked by a producer.
+// Records are append-only and tamper-evident: each record includes the hash
+// of the previous one, so removing or modifying a record breaks the chain.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Outcomes of audited operations.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Record is a single audit log entry.
+type Record struct {
+	Time          time.Time           `json:"time"`
+	Operation     string              `json:"operation"`
+	CorrelationID string              `json:"correlation_id,omitempty"`
+	AccessID      string              `json:"access_id,omitempty"`
+	ItemName      string              `json:"item_name,omitempty"`
+	SubClaims     map[string][]string `json:"sub_claims,omitempty"`
+	Fingerprint   string              `json:"fingerprint"`
+	IDs           []string            `json:"ids,omitempty"`
+	CertSerial    string              `json:"cert_serial,omitempty"`
+	Outcome       string              `json:"outcome"`
+	ErrorCode     string              `json:"error_code,omitempty"`
+	PrevHash      string              `json:"prev_hash"`
+	Hash          string              `json:"hash"`
+}
+
+// Sink stores audit records. Implementations must only append records,
+// never modify or remove them.
+type Sink interface {
+	Write(ctx context.Context, r *Record) error
+	Close() error
+}
+
+// ChainResumer is implemented by sinks that can read back the hash of the
+// last stored record, so that the chain continues across restarts.
+type ChainResumer interface {
+	LastHash() (string, error)
+}
+
+// Option is a single configuration parameter used by audit Logger.
+type Option func(*Logger)
+
+// WithSubClaims limits the sub-claims stored in audit records to the
+// provided names. By default, every sub-claim is stored.
+func WithSubClaims(names ...string) Option {
+	return func(l *Logger) {
+		l.subClaims = make(map[string]bool, len(names))
+		for _, n := range names {
+			l.subClaims[n] = true
+		}
+	}
+}
+
+// Logger hash-chains records and writes them to a sink.
+type Logger struct {
+	sink      Sink
+	subClaims map[string]bool
+
+	mu       sync.Mutex
+	lastHash string
+}
+
+// New creates a new audit logger that writes to the provided sink.
+func New(sink Sink, opts ...Option) (*Logger, error) {
+	l := &Logger{sink: sink}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if cr, ok := sink.(ChainResumer); ok {
+		h, err := cr.LastHash()
+		if err != nil {
+			return nil, fmt.Errorf("can't resume audit chain: %w", err)
+		}
+
+		l.lastHash = h
+	}
+
+	return l, nil
+}
+
+// Log fills in time, filters sub-claims, chains the record to the previous
+// one and writes it. Records are written one at a time, so sinks should
+// return quickly. A *SecondaryError means that the record was stored.
+func (l *Logger) Log(ctx context.Context, r *Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+
+	if l.subClaims != nil {
+		filtered := make(map[string][]string, len(l.subClaims))
+
+		for k, v := range r.SubClaims {
+			if l.subClaims[k] {
+				filtered[k] = v
+			}
+		}
+
+		r.SubClaims = filtered
+	}
+
+	r.PrevHash = l.lastHash
+	r.Hash = ""
+
+	h, err := hashRecord(r)
+	if err != nil {
+		return err
+	}
+
+	r.Hash = h
+
+	// records stored by the primary sink of a MultiSink are a part of the
+	// chain, even if other sinks failed
+	err = l.sink.Write(ctx, r)
+
+	var secondary *SecondaryError
+	if err != nil && !errors.As(err, &secondary) {
+		return fmt.Errorf("can't write audit record: %w", err)
+	}
+
+	l.lastHash = h
+
+	return err
+}
+
+// Close closes the underlying sink.
+func (l *Logger) Close() error {
+	return l.sink.Close()
+}
+
+// Verify checks that the provided records form an unbroken chain, and
+// returns the index of the first record that doesn't match, or -1.
+func Verify(records []*Record) (int, error) {
+	prev := ""
+	if len(records) > 0 {
+		prev = records[0].PrevHash
+	}
+
+	for i, r := range records {
+		if r.PrevHash != prev {
+			return i, nil
+		}
+
+		c := *r
+		c.Hash = ""
+
+		h, err := hashRecord(&c)
+		if err != nil {
+			return i, err
+		}
+
+		if h != r.Hash {
+			return i, nil
+		}
+
+		prev = r.Hash
+	}
+
+	return -1, nil
+}
+
+// Fingerprint returns a stable identifier of a request body.
+func Fingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func hashRecord(r *Record) (string, error) {
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("can't marshal audit record: %w", err)
+	}
+
+	sum := sha256.Sum256(bs)
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,201 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingSink fails every write.
+type failingSink struct{}
+
+func (failingSink) Write(context.Context, *Record) error { return errors.New("sink is down") }
+func (failingSink) Close() error                         { return nil }
+
+func readRecords(t *testing.T, path string) []*Record {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	var records []*Record
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+
+		records = append(records, &r)
+	}
+
+	return records
+}
+
+func TestChainAdvancesWhenSecondarySinkFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	file, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := New(MultiSink{file, failingSink{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		err := l.Log(context.Background(), &Record{Operation: "create"})
+
+		var secondary *SecondaryError
+		if !errors.As(err, &secondary) {
+			t.Fatalf("Log() returned %v, want *SecondaryError", err)
+		}
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readRecords(t, path)
+	if len(records) != 3 {
+		t.Fatalf("file has %d records, want 3", len(records))
+	}
+
+	if i, err := Verify(records); i != -1 || err != nil {
+		t.Errorf("Verify() = %d, %v on an untouched file", i, err)
+	}
+}
+
+func TestChainDoesntAdvanceWhenPrimarySinkFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	file, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a file sink that was closed fails every write
+	closed, err := NewFileSink(filepath.Join(t.TempDir(), "closed.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = closed.Close()
+
+	primary := &switchSink{Sink: file}
+
+	l, err := New(primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Log(context.Background(), &Record{Operation: "create"}); err != nil {
+		t.Fatal(err)
+	}
+
+	primary.set(closed)
+
+	if err := l.Log(context.Background(), &Record{Operation: "revoke"}); err == nil {
+		t.Fatal("Log() succeeded although the primary sink failed")
+	}
+
+	primary.set(file)
+
+	if err := l.Log(context.Background(), &Record{Operation: "revoke"}); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readRecords(t, path)
+	if i, err := Verify(records); i != -1 || err != nil {
+		t.Errorf("Verify() = %d, %v after a failed write", i, err)
+	}
+}
+
+// switchSink writes to a sink that can be replaced.
+type switchSink struct {
+	mu sync.Mutex
+	Sink
+}
+
+func (s *switchSink) set(sink Sink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sink = sink
+}
+
+func (s *switchSink) Write(ctx context.Context, r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Sink.Write(ctx, r)
+}
+
+func TestWebhookSinkDoesntBlockLog(t *testing.T) {
+	release := make(chan struct{})
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+
+		var rec Record
+		bs, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(bs, &rec)
+
+		mu.Lock()
+		received = append(received, rec.Operation)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	l, err := New(NewWebhookSink(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	for _, op := range []string{"create", "revoke", "rotate"} {
+		if err := l.Log(context.Background(), &Record{Operation: op}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Log() waited %v for the webhook", d)
+	}
+
+	close(release)
+
+	// Close delivers queued records
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(received) != 3 || received[0] != "create" || received[2] != "rotate" {
+		t.Errorf("webhook received %v, want records in order", received)
+	}
+}
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileSink appends records to a file as JSON lines.
+type FileSink struct {
+	path string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileSink opens (or creates) the provided file in append-only mode.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("can't open audit log %s: %w", path, err)
+	}
+
+	return &FileSink{path: path, f: f}, nil
+}
+
+// Write implements Sink. Every record is synced to disk before returning.
+func (s *FileSink) Write(_ context.Context, r *Record) error {
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("can't marshal audit record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.f.Write(append(bs, '\n')); err != nil {
+		return err
+	}
+
+	return s.f.Sync()
+}
+
+// Close implements Sink.
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// LastHash implements ChainResumer.
+func (s *FileSink) LastHash() (string, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	var last Record
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+
+		if err := json.Unmarshal(sc.Bytes(), &last); err != nil {
+			return "", fmt.Errorf("corrupted audit log %s: %w", s.path, err)
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+
+	return last.Hash, nil
+}
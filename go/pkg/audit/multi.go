@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"context"
+	"errors"
+)
+
+// MultiSink writes every record to all of the provided sinks. The first sink
+// is the primary one: a record is a part of the chain once the primary sink
+// stored it, even if other sinks fail.
+type MultiSink []Sink
+
+// SecondaryError is returned by MultiSink when the primary sink stored a
+// record, but some of the other sinks didn't. Logger still chains the next
+// record to it.
+type SecondaryError struct {
+	Err error
+}
+
+func (e *SecondaryError) Error() string {
+	return "audit record was stored, but not written to every sink: " + e.Err.Error()
+}
+
+func (e *SecondaryError) Unwrap() error {
+	return e.Err
+}
+
+// Write implements Sink. Records the primary sink failed to store aren't
+// written to the other sinks, since they aren't a part of the chain.
+// Otherwise, the record is written to every other sink even if some of them
+// fail, and their failures are reported as *SecondaryError.
+func (m MultiSink) Write(ctx context.Context, r *Record) error {
+	if len(m) == 0 {
+		return nil
+	}
+
+	if err := m[0].Write(ctx, r); err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, s := range m[1:] {
+		if err := s.Write(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &SecondaryError{Err: errors.Join(errs...)}
+	}
+
+	return nil
+}
+
+// Close implements Sink.
+func (m MultiSink) Close() error {
+	var errs []error
+
+	for _, s := range m {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// LastHash implements ChainResumer using the primary sink, if it supports
+// it.
+func (m MultiSink) LastHash() (string, error) {
+	if len(m) == 0 {
+		return "", nil
+	}
+
+	if cr, ok := m[0].(ChainResumer); ok {
+		return cr.LastHash()
+	}
+
+	return "", nil
+}
@@ -0,0 +1,41 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+)
+
+// SyslogSink sends records to syslog as JSON messages.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to a syslog daemon. If network and address are
+// empty, the local syslog daemon is used.
+func NewSyslogSink(network, address, tag string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to syslog: %w", err)
+	}
+
+	return &SyslogSink{w: w}, nil
+}
+
+// Write implements Sink.
+func (s *SyslogSink) Write(_ context.Context, r *Record) error {
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("can't marshal audit record: %w", err)
+	}
+
+	return s.w.Info(string(bs))
+}
+
+// Close implements Sink.
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,26 @@
+//go:build windows || plan9
+
+package audit
+
+import (
+	"context"
+	"errors"
+)
+
+// SyslogSink is not supported on this platform.
+type SyslogSink struct{}
+
+// NewSyslogSink always fails on this platform.
+func NewSyslogSink(_, _, _ string) (*SyslogSink, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
+
+// Write implements Sink.
+func (s *SyslogSink) Write(context.Context, *Record) error {
+	return errors.New("syslog is not supported on this platform")
+}
+
+// Close implements Sink.
+func (s *SyslogSink) Close() error {
+	return nil
+}
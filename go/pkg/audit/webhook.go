@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// webhookQueueSize is the number of records waiting for delivery before
+	// new records are rejected.
+	webhookQueueSize = 1024
+
+	// webhookAttempts is the number of attempts to deliver each record.
+	webhookAttempts = 3
+
+	// webhookCloseTimeout limits the time Close waits for queued records
+	// to be delivered.
+	webhookCloseTimeout = 30 * time.Second
+)
+
+var errWebhookClosed = errors.New("audit webhook sink is closed")
+
+// WebhookSink forwards records to an HTTP endpoint, one POST request with a
+// JSON body per record. Records are queued and delivered in order by a
+// background goroutine, so that a slow endpoint doesn't delay operations.
+// Records that can't be delivered are logged and dropped, so this sink
+// shouldn't be the primary sink of a MultiSink.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+	queue  chan []byte
+
+	// ctx is canceled when Close gives up waiting for queued records
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewWebhookSink creates a sink that forwards records to the provided URL.
+func NewWebhookSink(url string) *WebhookSink {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan []byte, webhookQueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Write implements Sink. It only queues the record, and fails if the queue
+// is full.
+func (s *WebhookSink) Write(_ context.Context, r *Record) error {
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("can't marshal audit record: %w", err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return errWebhookClosed
+	}
+
+	select {
+	case s.queue <- bs:
+		return nil
+	default:
+		return errors.New("audit webhook queue is full")
+	}
+}
+
+// Close implements Sink. It waits for queued records to be delivered, for up
+// to 30 seconds.
+func (s *WebhookSink) Close() error {
+	s.mu.Lock()
+
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	timer := time.NewTimer(webhookCloseTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-timer.C:
+		s.cancel()
+		<-s.done
+
+		return errors.New("audit webhook sink was closed before every record was delivered")
+	}
+}
+
+// run delivers queued records until the queue is closed.
+func (s *WebhookSink) run() {
+	defer close(s.done)
+	defer s.cancel()
+
+	for bs := range s.queue {
+		if err := s.deliver(bs); err != nil {
+			slog.Error("failed to deliver audit record", "url", s.url, "error", err)
+		}
+	}
+}
+
+// deliver sends a single record, retrying failed attempts with a growing
+// delay.
+func (s *WebhookSink) deliver(bs []byte) error {
+	var err error
+
+	for attempt := 0; attempt < webhookAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * time.Second):
+			case <-s.ctx.Done():
+				return errors.Join(err, s.ctx.Err())
+			}
+		}
+
+		if err = s.post(bs); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func (s *WebhookSink) post(bs []byte) error {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.url, bytes.NewReader(bs))
+	if err != nil {
+		return fmt.Errorf("can't create audit request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("audit request failed: %w", err)
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected audit response code %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -5,66 +5,147 @@ package auth
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const validationURL = "https://auth.akeyless.io/validate-producer-credentials"
 
+var tracer = otel.Tracer("github.com/akeylesslabs/custom-producer/go/pkg/auth")
+
 // Authenticate validates that the provided credentials belong to the
 // provided access ID, and optionally makes additional assertions.
 //
 // It uses Akeyless authentication service to confirm request initiator's
 // identity.
-func Authenticate(ctx context.Context, creds string, accessID string, opts ...Option) error {
+func Authenticate(ctx context.Context, creds string, accessID string, opts ...Option) (err error) {
+	ctx, span := tracer.Start(ctx, "auth.Authenticate", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+
 	o := &options{}
 
 	for _, opt := range opts {
 		opt(o)
 	}
 
+	_, err = validate(ctx, creds, accessID, o.itemName)
+
+	return err
+}
+
+// Identity is the producer that made a request.
+type Identity struct {
+	AccessID string
+	ItemName string
+}
+
+// Identify validates that the provided credentials belong to the provided
+// access ID, like Authenticate, and returns the item they were issued to.
+// It allows to select one of several producers that share an access ID with
+// a single call to Akeyless authentication service.
+func Identify(ctx context.Context, creds string, accessID string) (_ *Identity, err error) {
+	ctx, span := tracer.Start(ctx, "auth.Identify", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+
+	reqParams, err := validate(ctx, creds, accessID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	itemName, _ := reqParams["item_name"].(string)
+	if itemName == "" {
+		return nil, fmt.Errorf("validation response has no item name")
+	}
+
+	return &Identity{AccessID: accessID, ItemName: itemName}, nil
+}
+
+// validate sends the credentials to Akeyless authentication service, and
+// returns the parameters of the validated request.
+func validate(ctx context.Context, creds string, accessID string, itemName string) (map[string]interface{}, error) {
+	span := trace.SpanFromContext(ctx)
+
 	bs, err := json.Marshal(map[string]interface{}{
 		"creds":              creds,
 		"expected_access_id": accessID,
-		"expected_item_name": o.itemName,
+		"expected_item_name": itemName,
 	})
 	if err != nil {
-		return fmt.Errorf("can't marshal validation request: %w", err)
+		return nil, fmt.Errorf("can't marshal validation request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, validationURL, bytes.NewReader(bs))
 	if err != nil {
-		return fmt.Errorf("can't create validation request: %w", err)
+		return nil, fmt.Errorf("can't create validation request: %w", err)
 	}
 
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("validation request failed: %w", err)
+		return nil, fmt.Errorf("validation request failed: %w", err)
 	}
 
+	span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
+
 	defer func() { _ = res.Body.Close() }()
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return fmt.Errorf("can't read validation response body: %w", err)
+		return nil, fmt.Errorf("can't read validation response body: %w", err)
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected validation response code %d: %s", res.StatusCode, string(body))
+		return nil, fmt.Errorf("unexpected validation response code %d: %s", res.StatusCode, string(body))
 	}
 
 	var reqParams map[string]interface{}
 	if err := json.Unmarshal(body, &reqParams); err != nil {
-		return fmt.Errorf("can't marshal validation response body '%s': %w", string(body), err)
+		return nil, fmt.Errorf("can't marshal validation response body '%s': %w", string(body), err)
 	}
 
 	if accessID != reqParams["access_id"] {
-		return fmt.Errorf("mismatched access id")
+		return nil, fmt.Errorf("mismatched access id")
 	}
 
-	return nil
+	return reqParams, nil
+}
+
+// Expiry returns the expiration time of credentials that are a JWT with an
+// "exp" claim. Other credentials are opaque, and their expiration time isn't
+// known. The signature isn't verified, so the result may only be trusted
+// after the credentials are authenticated.
+func Expiry(creds string) (time.Time, bool) {
+	parts := strings.Split(creds, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	bs, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+
+	if err := json.Unmarshal(bs, &claims); err != nil || claims.Exp <= 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(claims.Exp, 0), true
 }
 
 // Option is an optional authentication assertion to be made.
@@ -81,3 +162,25 @@ func WithAllowedItemName(name string) Option {
 		o.itemName = name
 	}
 }
+
+// Ping checks that Akeyless authentication service is reachable. Any HTTP
+// response means the service is up, since validation requires credentials.
+func Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, validationURL, nil)
+	if err != nil {
+		return fmt.Errorf("can't create ping request: %w", err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("auth service is unreachable: %w", err)
+	}
+
+	_ = res.Body.Close()
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("auth service responded with %d", res.StatusCode)
+	}
+
+	return nil
+}
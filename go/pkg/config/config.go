@@ -0,0 +1,201 @@
+// Package config loads producer configuration from YAML, TOML or JSON files,
+// with environment variables overriding values of the file.
+//
+// Fields are mapped to environment variables using `env` struct tags. Nested
+// structs are traversed, and slices are read from comma separated values:
+//
+//	type Config struct {
+//		AccessID string   `yaml:"access_id" toml:"access_id" json:"access_id" env:"AKEYLESS_ACCESS_ID"`
+//		Hosts    []string `yaml:"hosts" toml:"hosts" json:"hosts" env:"HOSTS"`
+//	}
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Validator is implemented by configuration types that check their values
+// after loading.
+type Validator interface {
+	Validate() error
+}
+
+// Load reads the file at path into v, applies environment overrides and
+// validates the result. The format is chosen by the file extension: ".yaml",
+// ".yml", ".toml" or ".json". Unknown fields are rejected. If path is empty,
+// only environment variables are used.
+func Load(path string, v interface{}) error {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("can't read config file: %w", err)
+		}
+
+		if err := decode(path, data, v); err != nil {
+			return fmt.Errorf("can't parse config file %s: %w", path, err)
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(v).Elem()); err != nil {
+		return err
+	}
+
+	if val, ok := v.(Validator); ok {
+		if err := val.Validate(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func decode(path string, data []byte, v interface{}) error {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+
+		// an empty file is a valid, empty config
+		if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		return nil
+	case ".toml":
+		md, err := toml.Decode(string(data), v)
+		if err != nil {
+			return err
+		}
+
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown field '%s'", undecoded[0])
+		}
+
+		return nil
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+
+		return dec.Decode(v)
+	default:
+		return fmt.Errorf("unsupported format '%s'", ext)
+	}
+}
+
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// applyEnv sets fields of v from environment variables named by their `env`
+// tags.
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Tag.Get("env")
+		if name == "" {
+			if f.Type.Kind() == reflect.Struct && !reflect.PointerTo(f.Type).Implements(textUnmarshaler) {
+				if err := applyEnv(fv); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		if err := setValue(fv, s); err != nil {
+			return fmt.Errorf("invalid %s '%s': %w", name, s, err)
+		}
+	}
+
+	return nil
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshaler) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+	case reflect.Slice:
+		var items []string
+
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		sl := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(sl.Index(i), item); err != nil {
+				return err
+			}
+		}
+
+		v.Set(sl)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// Duration is a time.Duration that is written as a string, such as "30s",
+// in every supported format.
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	Addr string `yaml:"addr" toml:"addr" json:"addr" env:"TEST_SERVER_ADDR"`
+}
+
+type testConfig struct {
+	AccessID string     `yaml:"access_id" toml:"access_id" json:"access_id" env:"TEST_ACCESS_ID"`
+	Port     int        `yaml:"port" toml:"port" json:"port" env:"TEST_PORT"`
+	Debug    bool       `yaml:"debug" toml:"debug" json:"debug" env:"TEST_DEBUG"`
+	Timeout  Duration   `yaml:"timeout" toml:"timeout" json:"timeout" env:"TEST_TIMEOUT"`
+	Hosts    []string   `yaml:"hosts" toml:"hosts" json:"hosts" env:"TEST_HOSTS"`
+	Server   testServer `yaml:"server" toml:"server" json:"server"`
+}
+
+func (c *testConfig) Validate() error {
+	if c.AccessID == "" {
+		return errors.New("access_id is required")
+	}
+
+	return nil
+}
+
+// writeFile writes a config file with the provided name to a temporary
+// directory, and returns its path.
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+var fileConfig = testConfig{
+	AccessID: "p-file",
+	Port:     8443,
+	Timeout:  Duration(30 * time.Second),
+	Hosts:    []string{"a", "b"},
+	Server:   testServer{Addr: ":8443"},
+}
+
+func TestLoadFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "access_id: p-file\nport: 8443\ntimeout: 30s\nhosts: [a, b]\nserver:\n  addr: \":8443\"\n",
+		"config.toml": "access_id = \"p-file\"\nport = 8443\ntimeout = \"30s\"\nhosts = [\"a\", \"b\"]\n[server]\naddr = \":8443\"\n",
+		"config.json": `{"access_id":"p-file","port":8443,"timeout":"30s","hosts":["a","b"],"server":{"addr":":8443"}}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			var cfg testConfig
+			if err := Load(writeFile(t, name, content), &cfg); err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(cfg, fileConfig) {
+				t.Errorf("Load() = %+v, want %+v", cfg, fileConfig)
+			}
+		})
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", "access_id: p-file\nport: 8443\ntimeout: 30s\nhosts: [a, b]\nserver:\n  addr: \":8443\"\n")
+
+	t.Setenv("TEST_PORT", "9443")
+	t.Setenv("TEST_DEBUG", "true")
+	t.Setenv("TEST_HOSTS", "c, ,d")
+	t.Setenv("TEST_SERVER_ADDR", ":9443")
+
+	var cfg testConfig
+	if err := Load(path, &cfg); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	want := fileConfig
+	want.Port = 9443
+	want.Debug = true
+	want.Hosts = []string{"c", "d"}
+	want.Server.Addr = ":9443"
+
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Load() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestLoadEnvOnly(t *testing.T) {
+	t.Setenv("TEST_ACCESS_ID", "p-env")
+	t.Setenv("TEST_TIMEOUT", "1m")
+
+	var cfg testConfig
+	if err := Load("", &cfg); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.AccessID != "p-env" || cfg.Timeout != Duration(time.Minute) {
+		t.Errorf("Load() = %+v", cfg)
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	tests := []struct {
+		env   string
+		value string
+	}{
+		{"TEST_TIMEOUT", "30"},
+		{"TEST_TIMEOUT", "soon"},
+		{"TEST_PORT", "8443x"},
+		{"TEST_PORT", "99999999999999999999"},
+		{"TEST_DEBUG", "yes please"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env+"="+tt.value, func(t *testing.T) {
+			t.Setenv("TEST_ACCESS_ID", "p-env")
+			t.Setenv(tt.env, tt.value)
+
+			var cfg testConfig
+
+			err := Load("", &cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.env) {
+				t.Errorf("Load() = %v, want an error naming %s", err, tt.env)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.yaml", "access_id: p-file\ntimeout: 30\n"},
+		{"config.yaml", "access_id: p-file\nport: many\n"},
+		{"config.yaml", "access_id: p-file\nunknown: true\n"},
+		{"config.toml", "access_id = \"p-file\"\nunknown = true\n"},
+		{"config.json", `{"access_id":"p-file","timeout":"30 seconds"}`},
+		{"config.json", `{"access_id":"p-file","unknown":true}`},
+		{"config.ini", "access_id=p-file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg testConfig
+			if err := Load(writeFile(t, tt.name, tt.content), &cfg); err == nil {
+				t.Errorf("Load() of %q succeeded", tt.content)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	var cfg testConfig
+	if err := Load(filepath.Join(t.TempDir(), "missing.yaml"), &cfg); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load() = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestLoadMissingRequired(t *testing.T) {
+	// an empty file is valid, but doesn't set required values
+	path := writeFile(t, "config.yaml", "")
+
+	var cfg testConfig
+
+	err := Load(path, &cfg)
+	if err == nil || !strings.Contains(err.Error(), "access_id is required") {
+		t.Errorf("Load() = %v, want access_id to be required", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := writeFile(t, "config.yaml", "port: 1\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloaded := make(chan struct{}, 1)
+
+	go Watch(ctx, path, 10*time.Millisecond, func() { reloaded <- struct{}{} })
+
+	// the first poll only reads the file
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case <-reloaded:
+		t.Fatal("unchanged config was reloaded")
+	default:
+	}
+
+	if err := os.WriteFile(path, []byte("port: 2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Error("changed config wasn't reloaded")
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+)
+
+// DefaultWatchInterval is how often Watch checks the config file for changes.
+const DefaultWatchInterval = 10 * time.Second
+
+// Watch calls reload when the process receives SIGHUP, or when the content of
+// the file at path changes, until ctx is done. The file is polled rather than
+// watched for events, since tools such as Kubernetes replace mounted files by
+// swapping symlinks. If path is empty, only SIGHUP is handled.
+func Watch(ctx context.Context, path string, interval time.Duration, reload func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := fileSum(ctx, path)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logging.FromContext(ctx).Info("reloading config on SIGHUP")
+			last = fileSum(ctx, path)
+			reload()
+		case <-ticker.C:
+			if path == "" {
+				continue
+			}
+
+			if sum := fileSum(ctx, path); sum != last && sum != "" {
+				logging.FromContext(ctx).Info("reloading changed config file", "path", path)
+				last = sum
+				reload()
+			}
+		}
+	}
+}
+
+func fileSum(ctx context.Context, path string) string {
+	if path == "" {
+		return ""
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logging.FromContext(ctx).Error("can't read config file", "path", path, "error", err)
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+
+	return string(sum[:])
+}
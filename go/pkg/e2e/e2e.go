@@ -0,0 +1,169 @@
+// Package e2e encrypts producer responses end-to-end to a public key of the
+// final consumer, so that proxies terminating TLS in between can't read
+// issued credentials. Responses are encrypted as JWE in compact
+// serialization, and can be decrypted with Decrypt or any JOSE library.
+package e2e
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// ContentType is set in "cty" header of encrypted responses.
+const ContentType = "application/json"
+
+// ParsePublicKey parses an RSA or ECDSA public key in PEM (PKIX) or JWK
+// format. RSA keys must be at least 2048 bits long, and ECDSA keys must use
+// P-256, P-384 or P-521 curve.
+func ParsePublicKey(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+
+	var key interface{}
+
+	if strings.HasPrefix(s, "{") {
+		var jwk jose.JSONWebKey
+		if err := jwk.UnmarshalJSON([]byte(s)); err != nil {
+			return nil, fmt.Errorf("can't parse JWK: %w", err)
+		}
+
+		if !jwk.IsPublic() {
+			return nil, errors.New("JWK must be a public key")
+		}
+
+		key = jwk.Key
+	} else {
+		block, _ := pem.Decode([]byte(s))
+		if block == nil {
+			return nil, errors.New("key must be a PEM encoded public key or a JWK")
+		}
+
+		var err error
+
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse public key: %w", err)
+		}
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits long")
+		}
+	case *ecdsa.PublicKey:
+		// ECDH-ES is only defined for NIST curves
+		switch k.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+
+	return key, nil
+}
+
+// Encrypt marshals v as JSON and encrypts it to the provided public key. RSA
+// keys use RSA-OAEP-256, ECDSA keys use ECDH-ES+A256KW, and the content is
+// encrypted with A256GCM.
+func Encrypt(v interface{}, key interface{}) (string, error) {
+	var alg jose.KeyAlgorithm
+
+	switch key.(type) {
+	case *rsa.PublicKey:
+		alg = jose.RSA_OAEP_256
+	case *ecdsa.PublicKey:
+		alg = jose.ECDH_ES_A256KW
+	default:
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+
+	enc, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{Algorithm: alg, Key: key},
+		(&jose.EncrypterOptions{}).WithContentType(ContentType))
+	if err != nil {
+		return "", fmt.Errorf("can't create encrypter: %w", err)
+	}
+
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("can't marshal response: %w", err)
+	}
+
+	obj, err := enc.Encrypt(bs)
+	if err != nil {
+		return "", fmt.Errorf("can't encrypt response: %w", err)
+	}
+
+	return obj.CompactSerialize()
+}
+
+// Decrypt decrypts a response encrypted with Encrypt using the private key
+// of the consumer (*rsa.PrivateKey or *ecdsa.PrivateKey), and unmarshals it
+// into v.
+func Decrypt(token string, key interface{}, v interface{}) error {
+	obj, err := jose.ParseEncrypted(token,
+		[]jose.KeyAlgorithm{jose.RSA_OAEP_256, jose.ECDH_ES_A256KW},
+		[]jose.ContentEncryption{jose.A256GCM})
+	if err != nil {
+		return fmt.Errorf("can't parse encrypted response: %w", err)
+	}
+
+	bs, err := obj.Decrypt(key)
+	if err != nil {
+		return fmt.Errorf("can't decrypt response: %w", err)
+	}
+
+	if err := json.Unmarshal(bs, v); err != nil {
+		return fmt.Errorf("can't unmarshal decrypted response: %w", err)
+	}
+
+	return nil
+}
+
+// DecryptCreateResponse decrypts the "response" field of a create response
+// body, as returned by /sync/create endpoint, into v.
+func DecryptCreateResponse(body []byte, key interface{}, v interface{}) error {
+	var res struct {
+		Response string `json:"response"`
+	}
+
+	if err := json.Unmarshal(body, &res); err != nil {
+		return fmt.Errorf("can't unmarshal create response: %w", err)
+	}
+
+	return Decrypt(res.Response, key, v)
+}
+
+// ParsePrivateKey parses an RSA or ECDSA private key in PEM (PKCS #8, PKCS #1
+// or SEC 1) format, to be used with Decrypt.
+func ParsePrivateKey(s string) (interface{}, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse private key: %w", err)
+	}
+
+	return key, nil
+}
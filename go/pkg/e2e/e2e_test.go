@@ -0,0 +1,208 @@
+package e2e
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+type secret struct {
+	Password string `json:"password"`
+}
+
+func pemPublicKey(t *testing.T, pub crypto.PublicKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func jwkPublicKey(t *testing.T, pub crypto.PublicKey) string {
+	t.Helper()
+
+	bs, err := jose.JSONWebKey{Key: pub}.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(bs)
+}
+
+// pemPrivateKey encodes key in PKCS #8, or in the format specific to its
+// type.
+func pemPrivateKey(t *testing.T, key crypto.Signer, pkcs8 bool) string {
+	t.Helper()
+
+	var (
+		block = &pem.Block{Type: "PRIVATE KEY"}
+		err   error
+	)
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if !pkcs8 {
+			block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}
+		}
+	case *ecdsa.PrivateKey:
+		if !pkcs8 {
+			block.Type = "EC PRIVATE KEY"
+			block.Bytes, err = x509.MarshalECPrivateKey(k)
+		}
+	}
+
+	if pkcs8 {
+		block.Bytes, err = x509.MarshalPKCS8PrivateKey(key)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(block))
+}
+
+func TestRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := map[string]crypto.Signer{"RSA": rsaKey}
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys[curve.Params().Name] = key
+	}
+
+	for name, key := range keys {
+		for format, encode := range map[string]func(*testing.T, crypto.PublicKey) string{"PEM": pemPublicKey, "JWK": jwkPublicKey} {
+			t.Run(name+" "+format, func(t *testing.T) {
+				pub, err := ParsePublicKey(encode(t, key.Public()))
+				if err != nil {
+					t.Fatalf("ParsePublicKey() failed: %v", err)
+				}
+
+				token, err := Encrypt(&secret{Password: "s3cr3t"}, pub)
+				if err != nil {
+					t.Fatalf("Encrypt() failed: %v", err)
+				}
+
+				if strings.Contains(token, "s3cr3t") || strings.Count(token, ".") != 4 {
+					t.Fatalf("Encrypt() = %q, want a compact JWE", token)
+				}
+
+				for _, pkcs8 := range []bool{true, false} {
+					priv, err := ParsePrivateKey(pemPrivateKey(t, key, pkcs8))
+					if err != nil {
+						t.Fatalf("ParsePrivateKey() failed: %v", err)
+					}
+
+					var got secret
+					if err := Decrypt(token, priv, &got); err != nil || got.Password != "s3cr3t" {
+						t.Errorf("Decrypt() = %+v, %v, want the encrypted value", got, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestDecryptFailures(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := Encrypt(&secret{Password: "s3cr3t"}, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(token, ".")
+	parts[3] = strings.Repeat("A", len(parts[3]))
+
+	tests := []struct {
+		name  string
+		token string
+		key   interface{}
+	}{
+		{"another key", token, other},
+		{"tampered ciphertext", strings.Join(parts, "."), key},
+		{"not a JWE", "s3cr3t", key},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got secret
+			if err := Decrypt(tt.token, tt.key, &got); err == nil {
+				t.Errorf("Decrypt() = %+v, want an error", got)
+			}
+		})
+	}
+}
+
+func TestParsePublicKeyRejected(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr string
+	}{
+		{"short RSA PEM", pemPublicKey(t, &weak.PublicKey), "at least 2048 bits"},
+		{"short RSA JWK", jwkPublicKey(t, &weak.PublicKey), "at least 2048 bits"},
+		{"P-224", pemPublicKey(t, &p224.PublicKey), "unsupported curve P-224"},
+		{"Ed25519", pemPublicKey(t, edPub), "unsupported key type"},
+		{"private JWK", jwkPublicKey(t, p256), "must be a public key"},
+		{"not a key", "s3cr3t", "PEM encoded public key or a JWK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePublicKey(tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParsePublicKey() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
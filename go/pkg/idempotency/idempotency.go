@@ -0,0 +1,145 @@
+// Package idempotency makes repeated operations return the result of the
+// first one. It is used to avoid issuing a second credential when Akeyless
+// gateway retries a create request that timed out.
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+)
+
+// Store keeps results of completed operations. Results include issued
+// credentials, so stores shared by several replicas must be protected
+// accordingly.
+type Store interface {
+	// Get returns the value stored for key, if any, and not yet expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value for the provided duration.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Cache runs operations at most once per key within a window. Concurrent
+// operations with the same key wait for the first one to complete and share
+// its result.
+type Cache struct {
+	store  Store
+	window time.Duration
+
+	mu       sync.Mutex
+	inflight map[string]*call
+}
+
+type call struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// New creates a new cache that keeps results in store for the provided
+// window.
+func New(store Store, window time.Duration) *Cache {
+	return &Cache{
+		store:    store,
+		window:   window,
+		inflight: make(map[string]*call),
+	}
+}
+
+// Do returns the stored result of key, or runs fn to get it. Only successful
+// results are stored, so a failed operation can be retried right away.
+// Replayed reports whether the result comes from an earlier call.
+func (c *Cache) Do(ctx context.Context, key string, fn func() ([]byte, error)) (value []byte, replayed bool, err error) {
+	if v, ok, err := c.store.Get(ctx, key); err != nil {
+		logging.FromContext(ctx).Error("can't read idempotency store", "error", err)
+	} else if ok {
+		return v, true, nil
+	}
+
+	c.mu.Lock()
+
+	if cl, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+
+		select {
+		case <-cl.done:
+			return cl.value, true, cl.err
+		case <-ctx.Done():
+			return nil, false, fmt.Errorf("can't wait for in-flight request: %w", ctx.Err())
+		}
+	}
+
+	cl := &call{done: make(chan struct{})}
+	c.inflight[key] = cl
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		c.mu.Unlock()
+
+		close(cl.done)
+	}()
+
+	cl.value, cl.err = fn()
+
+	if cl.err == nil {
+		if err := c.store.Set(ctx, key, cl.value, c.window); err != nil {
+			logging.FromContext(ctx).Error("can't write idempotency store", "error", err)
+		}
+	}
+
+	return cl.value, false, cl.err
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time
+}
+
+// Memory is an in-memory Store. Its entries are local to a single process.
+type Memory struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+// NewMemory creates a new in-memory store.
+func NewMemory() *Memory {
+	return &Memory{now: time.Now, entries: make(map[string]entry)}
+}
+
+// Get implements Store.
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[key]
+	if !ok || m.now().After(e.expires) {
+		return nil, false, nil
+	}
+
+	return e.value, true, nil
+}
+
+// Set implements Store. Expired entries are removed as new ones are added.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+
+	for k, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+
+	m.entries[key] = entry{value: value, expires: now.Add(ttl)}
+
+	return nil
+}
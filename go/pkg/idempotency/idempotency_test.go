@@ -0,0 +1,165 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoConcurrentDuplicates(t *testing.T) {
+	c := New(NewMemory(), time.Minute)
+
+	var (
+		calls   atomic.Int32
+		started = make(chan struct{})
+		finish  = make(chan struct{})
+	)
+
+	fn := func() ([]byte, error) {
+		calls.Add(1)
+		close(started)
+		<-finish
+
+		return []byte("first"), nil
+	}
+
+	type result struct {
+		value    string
+		replayed bool
+		err      error
+	}
+
+	results := make([]result, 2)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		v, replayed, err := c.Do(context.Background(), "k", fn)
+		results[0] = result{string(v), replayed, err}
+	}()
+
+	// the duplicate starts once the first call is in flight
+	<-started
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		v, replayed, err := c.Do(context.Background(), "k", fn)
+		results[1] = result{string(v), replayed, err}
+	}()
+
+	// give the duplicate time to find the in-flight call
+	time.Sleep(10 * time.Millisecond)
+	close(finish)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("operation ran %d times, want once", n)
+	}
+
+	for i, r := range results {
+		if r.err != nil || r.value != "first" {
+			t.Errorf("call %d = %+v, want the first result", i, r)
+		}
+	}
+
+	if results[0].replayed || !results[1].replayed {
+		t.Errorf("replayed = %t, %t, want only the duplicate replayed", results[0].replayed, results[1].replayed)
+	}
+}
+
+func TestDoWaitCanceled(t *testing.T) {
+	c := New(NewMemory(), time.Minute)
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+
+	t.Cleanup(func() { close(finish) })
+
+	go func() {
+		_, _, _ = c.Do(context.Background(), "k", func() ([]byte, error) {
+			close(started)
+			<-finish
+
+			return nil, nil
+		})
+	}()
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, _, err := c.Do(ctx, "k", func() ([]byte, error) {
+		t.Error("duplicate ran while the first call was in flight")
+		return nil, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDoFailureNotCached(t *testing.T) {
+	c := New(NewMemory(), time.Minute)
+	errFailed := errors.New("failed")
+
+	if _, _, err := c.Do(context.Background(), "k", func() ([]byte, error) { return nil, errFailed }); !errors.Is(err, errFailed) {
+		t.Fatalf("Do() = %v, want %v", err, errFailed)
+	}
+
+	v, replayed, err := c.Do(context.Background(), "k", func() ([]byte, error) { return []byte("retry"), nil })
+	if err != nil || replayed || string(v) != "retry" {
+		t.Errorf("retry = %q, %t, %v, want a new result", v, replayed, err)
+	}
+}
+
+func TestDoKeys(t *testing.T) {
+	c := New(NewMemory(), time.Minute)
+
+	for _, key := range []string{"a", "b"} {
+		v, replayed, err := c.Do(context.Background(), key, func() ([]byte, error) { return []byte(key), nil })
+		if err != nil || replayed || string(v) != key {
+			t.Errorf("Do(%s) = %q, %t, %v, want its own result", key, v, replayed, err)
+		}
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	now := time.Now()
+
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+
+	c := New(m, time.Minute)
+
+	run := func() (string, bool) {
+		v, replayed, err := c.Do(context.Background(), "k", func() ([]byte, error) { return []byte(now.String()), nil })
+		if err != nil {
+			t.Fatalf("Do() failed: %v", err)
+		}
+
+		return string(v), replayed
+	}
+
+	first, _ := run()
+
+	now = now.Add(time.Minute)
+
+	if v, replayed := run(); !replayed || v != first {
+		t.Errorf("Do() within the window = %q, %t, want the first result", v, replayed)
+	}
+
+	now = now.Add(time.Second)
+
+	if v, replayed := run(); replayed || v == first {
+		t.Errorf("Do() after the window = %q, %t, want a new result", v, replayed)
+	}
+}
@@ -0,0 +1,48 @@
+// Package logging provides a structured JSON logger shared by producer
+// binaries. Every logger created by this package masks sensitive values, such
+// as producer payloads, private keys, passwords and Akeyless credentials.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type ctxKey struct{}
+
+// New creates a JSON logger that writes to w and redacts sensitive
+// attributes.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}))
+}
+
+// ParseLevel converts a level name ("debug", "info", "warn" or "error") into
+// slog.Level. Unknown names result in info level.
+func ParseLevel(name string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return slog.LevelInfo
+	}
+
+	return l
+}
+
+// WithContext returns a copy of ctx that carries the provided logger.
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx, or the default logger. Loggers
+// stored by the webhook include the request ID of the current request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+
+	return slog.Default()
+}
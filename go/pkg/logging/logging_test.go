@@ -0,0 +1,117 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+const secret = "s3cr3t-value"
+
+func TestSecretsNeverLogged(t *testing.T) {
+	var input struct {
+		Domain int `json:"domain"`
+	}
+
+	// protocol.Input.Decode errors used to quote the whole input
+	decodeErr := protocol.Input(`{"domain":"` + secret + `"}`).Decode(&input)
+
+	tests := []struct {
+		name string
+		args []interface{}
+	}{
+		{"sensitive attribute", []interface{}{"password", secret}},
+		{"sensitive attribute variant", []interface{}{"Client-Secret", secret}},
+		{"creds header", []interface{}{"headers", http.Header{"Akeylesscreds": {secret}}}},
+		{"authorization header", []interface{}{"headers", map[string]string{"Authorization": "Bearer " + secret}}},
+		{"raw json", []interface{}{"body", json.RawMessage(`{"payload":"` + secret + `","nested":[{"private_key":"` + secret + `"}]}`)}},
+		{"invalid raw json", []interface{}{"body", json.RawMessage(`{"payload":"` + secret)}},
+		{"group", []interface{}{slog.Group("request", "token", secret)}},
+		{"error with json", []interface{}{"error", fmt.Errorf("unexpected response 500: {\"password\":\"%s\"}", secret)}},
+		{"error with truncated json", []interface{}{"error", fmt.Errorf("can't unmarshal '{\"secret\": \"%s\", \"a\":'", secret)}},
+		{"error with form", []interface{}{"error", fmt.Errorf("bad request: grant_type=password&password=%s&client_id=x", secret)}},
+		{"error with bearer token", []interface{}{"error", fmt.Errorf("request with 'Authorization: Bearer %s' failed", secret)}},
+		{"wrapped protocol error", []interface{}{"error", protocol.ErrInternal.Wrap(fmt.Errorf("token=%s", secret))}},
+		{"input decode error", []interface{}{"error", decodeErr}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			New(&buf, slog.LevelDebug).Info("message", tt.args...)
+
+			if strings.Contains(buf.String(), secret) {
+				t.Errorf("secret was logged: %s", buf.String())
+			}
+
+			if !strings.Contains(buf.String(), "message") {
+				t.Errorf("entry wasn't logged: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestRedactTextKeepsMessages(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{
+			in:   "invalid payload: can't decrypt payload: cipher: message authentication failed",
+			want: "invalid payload: can't decrypt payload: cipher: message authentication failed",
+		},
+		{
+			in:   `unexpected response 400: {"error":"invalid_grant","password":"x"}`,
+			want: `unexpected response 400: {"error":"invalid_grant","password":"[REDACTED]"}`,
+		},
+		{
+			in:   "user=admin&password=x",
+			want: "user=admin&password=[REDACTED]",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := RedactText(tt.in); got != tt.want {
+			t.Errorf("RedactText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRedactTextTruncates(t *testing.T) {
+	got := RedactText(strings.Repeat("é", maxErrorLength))
+
+	if len(got) > maxErrorLength+len("...(truncated)") {
+		t.Errorf("text of %d bytes wasn't truncated", len(got))
+	}
+
+	if !strings.HasSuffix(got, "...(truncated)") {
+		t.Errorf("truncated text doesn't say so: %q", got[len(got)-20:])
+	}
+
+	if !json.Valid([]byte(`"` + got + `"`)) {
+		t.Error("text was truncated in the middle of a character")
+	}
+}
+
+func TestErrorsStayErrors(t *testing.T) {
+	var buf bytes.Buffer
+
+	New(&buf, slog.LevelDebug).Error("failed", "error", errors.New("boom"))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("entry isn't JSON: %v", err)
+	}
+
+	if entry["error"] != "boom" {
+		t.Errorf("error was logged as %v, want boom", entry["error"])
+	}
+}
@@ -0,0 +1,213 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Redacted replaces every sensitive value.
+const Redacted = "[REDACTED]"
+
+// maxErrorLength limits the length of logged error messages, since they may
+// quote responses or requests of any size.
+const maxErrorLength = 1024
+
+// sensitiveKeys are matched against normalized attribute, JSON field and
+// header names: lower case, without "-" and "_".
+var sensitiveKeys = []string{
+	"payload",
+	"privatekey",
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"akeylesscreds",
+	"creds",
+	"authorization",
+}
+
+var (
+	// sensitiveName matches names that include a sensitive key.
+	sensitiveName = `[a-z0-9_-]*(?:` + separated(sensitiveKeys) + `)[a-z0-9_-]*`
+
+	// sensitiveText matches values of sensitive keys in free text, such as
+	// form encoded `password=x` and `"secret": "x"` in JSON that couldn't
+	// be parsed. Keys may be separated by "-" or "_", like in IsSensitive.
+	sensitiveText = regexp.MustCompile(`(?i)((?:` + sensitiveName + `\s*=|"` + sensitiveName + `"\s*:)\s*)("(?:[^"\\]|\\.)*"|'[^']*'|[^\s,;&}\]]+)`)
+	bearerToken   = regexp.MustCompile(`(?i)(bearer\s+)[a-z0-9._~+/=-]+`)
+)
+
+// separated returns an alternation of keys that allows "-" and "_" between
+// their characters.
+func separated(keys []string) string {
+	alts := make([]string, 0, len(keys))
+
+	for _, k := range keys {
+		alts = append(alts, strings.Join(strings.Split(k, ""), "[-_]?"))
+	}
+
+	return strings.Join(alts, "|")
+}
+
+// IsSensitive reports whether values stored under the provided key (an
+// attribute name, JSON field or HTTP header) must be redacted.
+func IsSensitive(key string) bool {
+	k := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(key))
+
+	for _, s := range sensitiveKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedactJSON masks sensitive fields in a JSON document, at any depth. Values
+// that aren't valid JSON are redacted entirely, since their content is
+// unknown.
+func RedactJSON(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		bs, _ := json.Marshal(Redacted)
+		return bs
+	}
+
+	bs, err := json.Marshal(redactValue(v))
+	if err != nil {
+		bs, _ = json.Marshal(Redacted)
+	}
+
+	return bs
+}
+
+// RedactHeaders returns a copy of the provided headers with sensitive values
+// masked.
+func RedactHeaders(h map[string]string) map[string]string {
+	out := make(map[string]string, len(h))
+
+	for k, v := range h {
+		if IsSensitive(k) {
+			v = Redacted
+		}
+
+		out[k] = v
+	}
+
+	return out
+}
+
+// RedactText masks sensitive values in free text, such as error messages
+// that quote a response or a request. JSON documents in the text are masked
+// like RedactJSON does, values that follow sensitive keys and bearer tokens
+// are redacted, and long texts are truncated.
+func RedactText(s string) string {
+	s = redactEmbeddedJSON(s)
+	s = sensitiveText.ReplaceAllStringFunc(s, func(m string) string {
+		kv := sensitiveText.FindStringSubmatch(m)
+
+		// quoted values stay quoted, so that JSON stays valid
+		if q := kv[2][0]; q == '"' || q == '\'' {
+			return kv[1] + string(q) + Redacted + string(q)
+		}
+
+		return kv[1] + Redacted
+	})
+	s = bearerToken.ReplaceAllString(s, "${1}"+Redacted)
+
+	if len(s) > maxErrorLength {
+		cut := maxErrorLength
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+
+		s = s[:cut] + "...(truncated)"
+	}
+
+	return s
+}
+
+// redactEmbeddedJSON replaces every JSON object or array in s with its
+// redacted version.
+func redactEmbeddedJSON(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); {
+		if s[i] != '{' && s[i] != '[' {
+			b.WriteByte(s[i])
+			i++
+
+			continue
+		}
+
+		dec := json.NewDecoder(strings.NewReader(s[i:]))
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			b.WriteByte(s[i])
+			i++
+
+			continue
+		}
+
+		b.Write(bytes.TrimSpace(RedactJSON(raw)))
+		i += int(dec.InputOffset())
+	}
+
+	return b.String()
+}
+
+func redactValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, val := range vv {
+			if IsSensitive(k) {
+				vv[k] = Redacted
+			} else {
+				vv[k] = redactValue(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range vv {
+			vv[i] = redactValue(val)
+		}
+	}
+
+	return v
+}
+
+// redactAttr is used as slog.HandlerOptions.ReplaceAttr.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch v := a.Value.Any().(type) {
+	case http.Header:
+		out := make(map[string]string, len(v))
+		for k := range v {
+			out[k] = v.Get(k)
+		}
+
+		return slog.Any(a.Key, RedactHeaders(out))
+	case map[string]string:
+		return slog.Any(a.Key, RedactHeaders(v))
+	case json.RawMessage:
+		return slog.Any(a.Key, RedactJSON(v))
+	case error:
+		// errors may quote requests and responses, which include
+		// secrets under names that aren't sensitive, for example, input
+		return slog.String(a.Key, RedactText(v.Error()))
+	}
+
+	return a
+}
@@ -0,0 +1,74 @@
+// Package password generates random passwords and user names using the
+// system random source.
+package password
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Character classes of generated passwords.
+const (
+	Lowercase = "abcdefghijklmnopqrstuvwxyz"
+	Uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Digits    = "0123456789"
+	// Symbols excludes quotes, backslashes and spaces, so that passwords
+	// are easy to paste into shells and config files.
+	Symbols = "!#%+-.=?@^_~"
+)
+
+// Generate returns a random password of the provided length that includes at
+// least one character of every class, for example, Lowercase and Digits,
+// so that it satisfies common password policies.
+func Generate(length int, classes ...string) (string, error) {
+	if len(classes) == 0 {
+		return "", errors.New("at least one character class is required")
+	}
+
+	if length < len(classes) {
+		return "", fmt.Errorf("password of %d characters can't include %d character classes", length, len(classes))
+	}
+
+	all := strings.Join(classes, "")
+
+	b := make([]byte, length)
+
+	for i := range b {
+		set := all
+		if i < len(classes) {
+			set = classes[i]
+		}
+
+		j, err := randomInt(len(set))
+		if err != nil {
+			return "", err
+		}
+
+		b[i] = set[j]
+	}
+
+	// move the characters of each class to random positions
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return string(b), nil
+}
+
+// randomInt returns a uniformly distributed integer in [0, n).
+func randomInt(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("can't read random data: %w", err)
+	}
+
+	return int(i.Int64()), nil
+}
@@ -0,0 +1,183 @@
+// Package payload decodes producer payloads into typed structs, and
+// encrypts them so that payloads stored in Akeyless are useless without a key
+// held only by the producer.
+//
+// Producers declare their payload as a struct, and decode it with Decode:
+//
+//	type Config struct {
+//		URL      string `json:"url" validate:"required"`
+//		Password string `json:"password" validate:"required"`
+//	}
+//
+//	cfg, err := payload.Decode[Config](r.Payload)
+//	if err != nil {
+//		return nil, err
+//	}
+package payload
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/validate"
+)
+
+// Prefix marks encrypted payloads. It is followed by base64 encoded nonce
+// and AES-256-GCM ciphertext.
+const Prefix = "enc:v1:"
+
+// KeySize is the size of payload encryption keys, in bytes.
+const KeySize = 32
+
+// EncryptionKeyField is the field of a JSON payload that may hold the public
+// key the webhook encrypts create responses to. It isn't a part of the
+// producer's payload, so Decode ignores it unless T declares it.
+const EncryptionKeyField = "encryption_key"
+
+// ErrInvalidPayload is returned when the payload can't be decrypted or
+// decoded, or doesn't match the declared struct. Violations, if any, are
+// listed in the error details.
+var ErrInvalidPayload = protocol.NewError("invalid_payload", http.StatusBadRequest, "invalid payload")
+
+// Decode decodes a JSON payload into a new T, and validates it using
+// `validate` struct tags. Unknown fields are rejected, except
+// EncryptionKeyField.
+func Decode[T any](payload string) (*T, error) {
+	var v T
+
+	if strings.TrimSpace(payload) != "" {
+		err := decodeStrict(payload, &v)
+		if err != nil {
+			if stripped, ok := withoutEncryptionKey(payload); ok {
+				v = *new(T)
+				err = decodeStrict(stripped, &v)
+			}
+		}
+
+		if err != nil {
+			return nil, ErrInvalidPayload.WithDetails([]validate.Violation{validate.DecodeError(err)}).Wrap(err)
+		}
+	}
+
+	if violations := validate.Struct(&v); len(violations) > 0 {
+		return nil, ErrInvalidPayload.WithDetails(violations)
+	}
+
+	return &v, nil
+}
+
+func decodeStrict(payload string, v interface{}) error {
+	dec := json.NewDecoder(strings.NewReader(payload))
+	dec.DisallowUnknownFields()
+
+	return dec.Decode(v)
+}
+
+// withoutEncryptionKey removes EncryptionKeyField from a JSON object. It
+// reports false if the payload isn't an object with that field.
+func withoutEncryptionKey(payload string) (string, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(payload), &fields); err != nil {
+		return "", false
+	}
+
+	if _, ok := fields[EncryptionKeyField]; !ok {
+		return "", false
+	}
+
+	delete(fields, EncryptionKeyField)
+
+	bs, err := json.Marshal(fields)
+	if err != nil {
+		return "", false
+	}
+
+	return string(bs), true
+}
+
+// ParseKey decodes a base64 encoded AES-256 key.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("can't decode payload key: %w", err)
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("payload key must be %d bytes long, got %d", KeySize, len(key))
+	}
+
+	return key, nil
+}
+
+// IsEncrypted reports whether the payload was encrypted with Encrypt.
+func IsEncrypted(payload string) bool {
+	return strings.HasPrefix(payload, Prefix)
+}
+
+// Encrypt encrypts the payload with the provided key. The result should be
+// stored in Akeyless instead of the plain payload.
+func Encrypt(key []byte, payload []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("can't generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, payload, []byte(Prefix))
+
+	return Prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a payload encrypted with Encrypt. Every key is tried in
+// turn, so that keys can be rotated without breaking existing payloads.
+func Decrypt(keys [][]byte, payload string) ([]byte, error) {
+	if !IsEncrypted(payload) {
+		return nil, errors.New("payload isn't encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(payload, Prefix))
+	if err != nil {
+		return nil, fmt.Errorf("can't decode encrypted payload: %w", err)
+	}
+
+	for _, key := range keys {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(sealed) < aead.NonceSize() {
+			return nil, errors.New("encrypted payload is too short")
+		}
+
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+		if plain, err := aead.Open(nil, nonce, ciphertext, []byte(Prefix)); err == nil {
+			return bytes.TrimSpace(plain), nil
+		}
+	}
+
+	return nil, errors.New("payload can't be decrypted with any of the keys")
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload key: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,167 @@
+package payload
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+type config struct {
+	URL string `json:"url" validate:"required"`
+}
+
+func TestDecodeIgnoresEncryptionKey(t *testing.T) {
+	cfg, err := Decode[config](`{"url":"https://example.com","encryption_key":"-----BEGIN PUBLIC KEY-----"}`)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+
+	if cfg.URL != "https://example.com" {
+		t.Errorf("url = %q", cfg.URL)
+	}
+}
+
+func TestDecodeKeepsDeclaredEncryptionKey(t *testing.T) {
+	type withKey struct {
+		URL string `json:"url"`
+		Key string `json:"encryption_key"`
+	}
+
+	cfg, err := Decode[withKey](`{"url":"u","encryption_key":"k"}`)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+
+	if cfg.Key != "k" {
+		t.Errorf("encryption_key = %q, want k", cfg.Key)
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"unknown field", `{"url":"u","user":"x"}`},
+		{"unknown field with encryption key", `{"url":"u","user":"x","encryption_key":"k"}`},
+		{"missing field", `{"encryption_key":"k"}`},
+		{"not an object", `"u"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode[config](tt.payload)
+
+			var pErr *protocol.Error
+			if !errors.As(err, &pErr) || pErr.Code != ErrInvalidPayload.Code {
+				t.Errorf("Decode() = %v, want %v", err, ErrInvalidPayload)
+			}
+		})
+	}
+}
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := newKey(t)
+	plain := []byte(`{"url":"https://example.com","password":"s3cr3t"}`)
+
+	enc, err := Encrypt(key, plain)
+	if err != nil {
+		t.Fatalf("Encrypt() failed: %v", err)
+	}
+
+	if !IsEncrypted(enc) || strings.Contains(enc, "s3cr3t") {
+		t.Fatalf("Encrypt() = %q, want an encrypted payload", enc)
+	}
+
+	// every payload has its own nonce
+	if again, _ := Encrypt(key, plain); again == enc {
+		t.Error("Encrypt() returned the same ciphertext twice")
+	}
+
+	got, err := Decrypt([][]byte{key}, enc)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("Decrypt() = %s, %v, want %s", got, err, plain)
+	}
+}
+
+func TestDecryptRotatedKey(t *testing.T) {
+	current, old := newKey(t), newKey(t)
+
+	enc, err := Encrypt(old, []byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new key is listed first, and the old one is kept to decrypt
+	// payloads that weren't rotated yet
+	got, err := Decrypt([][]byte{current, old}, enc)
+	if err != nil || string(got) != "old" {
+		t.Errorf("Decrypt() = %s, %v, want old", got, err)
+	}
+}
+
+func TestDecryptFailures(t *testing.T) {
+	key := newKey(t)
+
+	enc, err := Encrypt(key, []byte("plain"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(enc, Prefix))
+	sealed[len(sealed)-1] ^= 1
+	tampered := Prefix + base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name    string
+		keys    [][]byte
+		payload string
+	}{
+		{"tampered ciphertext", [][]byte{key}, tampered},
+		{"another key", [][]byte{newKey(t)}, enc},
+		{"no keys", nil, enc},
+		{"invalid key", [][]byte{[]byte("short")}, enc},
+		{"truncated", [][]byte{key}, Prefix + base64.StdEncoding.EncodeToString(sealed[:4])},
+		{"not base64", [][]byte{key}, Prefix + "!"},
+		{"plain", [][]byte{key}, "plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Decrypt(tt.keys, tt.payload); err == nil {
+				t.Errorf("Decrypt() = %s, want an error", got)
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key := newKey(t)
+
+	got, err := ParseKey(" " + base64.StdEncoding.EncodeToString(key) + "\n")
+	if err != nil || !bytes.Equal(got, key) {
+		t.Errorf("ParseKey() = %x, %v, want %x", got, err, key)
+	}
+
+	for _, s := range []string{base64.StdEncoding.EncodeToString(key[:16]), "not base64!"} {
+		if _, err := ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package protocol includes types shared by Akeyless Custom Producer
+// implementations.
+package protocol
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Error codes returned in ErrorResponse. Producers may define their own codes
+// in addition to these.
+const (
+	CodeBadRequest         = "bad_request"
+	CodeInvalidInput       = "invalid_input"
+	CodeUnauthorized       = "unauthorized"
+	CodePreconditionFailed = "precondition_failed"
+	CodeRateLimited        = "rate_limited"
+	CodeUpstreamError      = "upstream_error"
+	CodeUnavailable        = "unavailable"
+	CodeInternalError      = "internal_error"
+)
+
+// ErrInternal is used for every error that isn't an *Error. Its message is
+// intentionally vague to avoid leaking internal details to the caller.
+var ErrInternal = NewError(CodeInternalError, http.StatusInternalServerError, "internal error")
+
+// Error is a typed error that producers return to control the response sent
+// back to Akeyless. Only Code, Message, Retryable and Details are sent to the
+// caller; the wrapped error is only logged.
+type Error struct {
+	// Code is a stable, machine readable error code.
+	Code string
+	// Status is the HTTP status code of the response.
+	Status int
+	// Message is a user-facing message. It must never include secrets or
+	// internal details.
+	Message string
+	// Retryable tells the caller whether the same request may succeed later.
+	Retryable bool
+	// Details is optional structured information about the error.
+	Details interface{}
+	// RetryAfter, if set, is sent in Retry-After header of the response.
+	RetryAfter time.Duration
+
+	err error
+}
+
+// NewError creates a new error with the provided code, status and message.
+func NewError(code string, status int, message string) *Error {
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.err == nil {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s: %s", e.Message, e.err)
+}
+
+// Unwrap returns the wrapped error, if any.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the target is an *Error with the same code. It allows
+// to use errors declared with NewError as sentinel values even after they are
+// wrapped or customized.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of this error that wraps the provided one.
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.err = err
+
+	return &c
+}
+
+// WithMessage returns a copy of this error with a different message.
+func (e *Error) WithMessage(format string, args ...interface{}) *Error {
+	c := *e
+	c.Message = fmt.Sprintf(format, args...)
+
+	return &c
+}
+
+// WithDetails returns a copy of this error with the provided details.
+func (e *Error) WithDetails(details interface{}) *Error {
+	c := *e
+	c.Details = details
+
+	return &c
+}
+
+// WithRetryAfter returns a copy of this error that asks the caller to retry
+// after the provided duration.
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	c := *e
+	c.RetryAfter = d
+
+	return &c
+}
+
+// ErrorResponse is the JSON envelope of every error response:
+//
+//	{
+//	  "error": {
+//	    "code": "invalid_input",
+//	    "message": "unknown profile 'foo'",
+//	    "correlation_id": "5f0c6c1a9ad4e3a1",
+//	    "retryable": false
+//	  }
+//	}
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody is the content of ErrorResponse.
+type ErrorBody struct {
+	Code          string      `json:"code"`
+	Message       string      `json:"message"`
+	CorrelationID string      `json:"correlation_id,omitempty"`
+	Retryable     bool        `json:"retryable"`
+	Details       interface{} `json:"details,omitempty"`
+}
+
+// NewErrorResponse converts the provided error into a response envelope.
+func NewErrorResponse(e *Error, correlationID string) *ErrorResponse {
+	return &ErrorResponse{
+		Error: ErrorBody{
+			Code:          e.Code,
+			Message:       e.Message,
+			CorrelationID: correlationID,
+			Retryable:     e.Retryable,
+			Details:       e.Details,
+		},
+	}
+}
@@ -0,0 +1,147 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+var blankStringBytes = []byte(`""`)
+
+// DryRunAccessID is the access ID of create requests made by Akeyless to
+// check the producer configuration while it is being set up. Producers
+// shouldn't create real credentials for these requests.
+const DryRunAccessID = "p-custom"
+
+// Producer is an implementation of Akeyless Custom Producer. Every producer
+// must support create and revoke operations.
+type Producer interface {
+	Create(context.Context, *CreateRequest) (*CreateResponse, error)
+	Revoke(context.Context, *RevokeRequest) (*RevokeResponse, error)
+}
+
+// Rotator is implemented by producers that support rotation of the admin
+// credentials stored in their payload.
+type Rotator interface {
+	Rotate(context.Context, *RotateRequest) (*RotateResponse, error)
+}
+
+// IDRevoker is implemented by producers that revoke credentials one at a
+// time. The webhook then revokes IDs concurrently, and reports only the IDs
+// that were revoked, so that the gateway retries only the failed ones.
+type IDRevoker interface {
+	RevokeID(ctx context.Context, payload string, id string) error
+}
+
+// Describer is implemented by producers that describe their input and
+// payload, so that the webhook can publish their schema at /sync/describe.
+type Describer interface {
+	Describe() Description
+}
+
+// Operations of producers.
+const (
+	OperationCreate = "create"
+	OperationRevoke = "revoke"
+	OperationRotate = "rotate"
+)
+
+// Description describes the input and payload of a producer. Input and
+// Payload are values of the types the producer decodes them into, for
+// example, Input{}, or nil if the producer doesn't use them.
+type Description struct {
+	Input   interface{}
+	Payload interface{}
+	// Operations lists the operations the producer supports, for
+	// producers that implement some of them only to satisfy an interface,
+	// for example, a Rotator whose Create always fails. If nil, create,
+	// revoke and, for a Rotator, rotate are supported.
+	Operations []string
+}
+
+// CreateRequest represents requests to /sync/create endpoint to create
+// temporary credentials.
+type CreateRequest struct {
+	Payload    string     `json:"payload"`
+	ClientInfo ClientInfo `json:"client_info"`
+	Input      Input      `json:"input,omitempty"`
+}
+
+// Input is the input provided with `get-dynamic-secret-value` operation. Its
+// structure is specific to each producer, so it is kept as raw JSON until the
+// producer decodes it.
+type Input json.RawMessage
+
+// UnmarshalJSON implements json.Unmarshaler. A blank string is treated as
+// missing input.
+func (i *Input) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, blankStringBytes) {
+		*i = nil
+		return nil
+	}
+
+	*i = append((*i)[:0], data...)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (i Input) MarshalJSON() ([]byte, error) {
+	if len(i) == 0 {
+		return []byte("null"), nil
+	}
+
+	return i, nil
+}
+
+// Decode unmarshals the input into the provided value. Missing input leaves
+// the value unchanged.
+func (i Input) Decode(v interface{}) error {
+	if len(i) == 0 {
+		return nil
+	}
+
+	// the input isn't quoted, since it may include secrets such as
+	// encryption keys
+	if err := json.Unmarshal(i, v); err != nil {
+		return fmt.Errorf("cannot unmarshal input: %w", err)
+	}
+
+	return nil
+}
+
+// ClientInfo wraps original user information, such as Access ID or sub-claims.
+type ClientInfo struct {
+	AccessID  string              `json:"access_id"`
+	SubClaims map[string][]string `json:"sub_claims"`
+}
+
+// CreateResponse is returned by "create" operation.
+type CreateResponse struct {
+	ID       string      `json:"id"`
+	Response interface{} `json:"response"`
+}
+
+// RevokeRequest represents revocation requests made by Akeyless Custom
+// Producer.
+type RevokeRequest struct {
+	Payload string   `json:"payload"`
+	IDs     []string `json:"ids"`
+}
+
+// RevokeResponse is returned by revoke operation.
+type RevokeResponse struct {
+	Revoked []string `json:"revoked"`
+	Message string   `json:"message,omitempty"`
+}
+
+// RotateRequest represents admin credentials rotation requests made by
+// Akeyless Custom Producer.
+type RotateRequest struct {
+	Payload string `json:"payload"`
+}
+
+// RotateResponse is returned by rotate operation.
+type RotateResponse struct {
+	Payload string `json:"payload"`
+}
@@ -0,0 +1,160 @@
+// Package ratelimit limits how often and how many producer operations can
+// run. Limiters are interfaces, so that replicas of a producer may share
+// their state through an external store.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter decides whether an operation identified by key is allowed now. If
+// it isn't, the returned duration tells when it may be retried.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (bool, time.Duration, error)
+}
+
+// Concurrency limits the number of operations running at the same time. If
+// a slot is available, the returned release function must be called once the
+// operation completes.
+type Concurrency interface {
+	Acquire(ctx context.Context) (release func(), ok bool, err error)
+}
+
+// Rate is a number of operations allowed per period. It is enforced as a
+// token bucket of Count tokens, refilled at Count per Period, so short bursts
+// up to Count are allowed.
+type Rate struct {
+	Count  int
+	Period time.Duration
+}
+
+// ParseRate parses rates in "count/period" format, where period is a Go
+// duration or one of "s", "m", "h" and "d". For example: "10/m", "100/24h".
+func ParseRate(s string) (Rate, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Rate{}, fmt.Errorf("invalid rate '%s': expected count/period", s)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || count <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate '%s': count must be a positive number", s)
+	}
+
+	var period time.Duration
+
+	switch p := strings.TrimSpace(parts[1]); p {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	case "d":
+		period = 24 * time.Hour
+	default:
+		period, err = time.ParseDuration(p)
+		if err != nil || period <= 0 {
+			return Rate{}, fmt.Errorf("invalid rate '%s': invalid period", s)
+		}
+	}
+
+	return Rate{Count: count, Period: period}, nil
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Memory is an in-memory token bucket Limiter. Its state is local to a single
+// process.
+type Memory struct {
+	rate Rate
+	now  func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewMemory creates a new in-memory limiter enforcing the provided rate per
+// key.
+func NewMemory(rate Rate) *Memory {
+	return &Memory{
+		rate:    rate,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow implements Limiter.
+func (m *Memory) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	perToken := m.rate.Period / time.Duration(m.rate.Count)
+	capacity := float64(m.rate.Count)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens += float64(now.Sub(b.last)) / float64(perToken)
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+
+	b.last = now
+
+	m.gc(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	return false, time.Duration((1 - b.tokens) * float64(perToken)), nil
+}
+
+// gc removes buckets that are full again, since they are equivalent to
+// missing ones.
+func (m *Memory) gc(now time.Time) {
+	if len(m.buckets) < 1024 {
+		return
+	}
+
+	for k, b := range m.buckets {
+		if now.Sub(b.last) >= m.rate.Period {
+			delete(m.buckets, k)
+		}
+	}
+}
+
+// Semaphore is an in-memory Concurrency limiter.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore creates a limiter that allows up to n concurrent operations.
+func NewSemaphore(n int) *Semaphore {
+	return &Semaphore{slots: make(chan struct{}, n)}
+}
+
+// Acquire implements Concurrency. It never waits: if no slot is available,
+// the operation is rejected right away.
+func (s *Semaphore) Acquire(_ context.Context) (func(), bool, error) {
+	select {
+	case s.slots <- struct{}{}:
+		return func() { <-s.slots }, true, nil
+	default:
+		return nil, false, nil
+	}
+}
@@ -0,0 +1,147 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestMemory creates a limiter whose clock is advanced by the returned
+// function.
+func newTestMemory(rate Rate) (*Memory, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	m := NewMemory(rate)
+	m.now = func() time.Time { return now }
+
+	return m, func(d time.Duration) { now = now.Add(d) }
+}
+
+// allowN calls Allow n times, and returns how many calls were allowed and
+// the retry delay of the last one.
+func allowN(t *testing.T, m *Memory, key string, n int) (int, time.Duration) {
+	t.Helper()
+
+	var (
+		allowed    int
+		retryAfter time.Duration
+	)
+
+	for i := 0; i < n; i++ {
+		ok, d, err := m.Allow(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Allow() failed: %v", err)
+		}
+
+		if ok {
+			allowed++
+		}
+
+		retryAfter = d
+	}
+
+	return allowed, retryAfter
+}
+
+func TestMemoryBurst(t *testing.T) {
+	m, _ := newTestMemory(Rate{Count: 3, Period: time.Minute})
+
+	allowed, retryAfter := allowN(t, m, "k", 5)
+	if allowed != 3 {
+		t.Errorf("allowed %d requests of a burst, want 3", allowed)
+	}
+
+	if retryAfter != 20*time.Second {
+		t.Errorf("retry after %s, want 20s", retryAfter)
+	}
+}
+
+func TestMemoryRefill(t *testing.T) {
+	m, advance := newTestMemory(Rate{Count: 3, Period: time.Minute})
+
+	allowN(t, m, "k", 3)
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    int
+	}{
+		{"partial token", 10 * time.Second, 0},
+		{"one token", 10 * time.Second, 1},
+		// buckets don't fill above their capacity
+		{"full bucket", time.Hour, 3},
+	}
+
+	for _, tt := range tests {
+		advance(tt.elapsed)
+
+		if allowed, _ := allowN(t, m, "k", 5); allowed != tt.want {
+			t.Errorf("%s: allowed %d requests, want %d", tt.name, allowed, tt.want)
+		}
+	}
+}
+
+func TestMemoryKeys(t *testing.T) {
+	m, _ := newTestMemory(Rate{Count: 1, Period: time.Hour})
+
+	if allowed, _ := allowN(t, m, "a", 2); allowed != 1 {
+		t.Fatalf("allowed %d requests of a, want 1", allowed)
+	}
+
+	// another key has its own bucket
+	if allowed, _ := allowN(t, m, "b", 2); allowed != 1 {
+		t.Errorf("allowed %d requests of b, want 1", allowed)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		wantErr bool
+	}{
+		{"10/m", Rate{10, time.Minute}, false},
+		{" 5 / h ", Rate{5, time.Hour}, false},
+		{"100/d", Rate{100, 24 * time.Hour}, false},
+		{"50/24h", Rate{50, 24 * time.Hour}, false},
+		{"1/s", Rate{1, time.Second}, false},
+		{"10", Rate{}, true},
+		{"0/m", Rate{}, true},
+		{"-1/m", Rate{}, true},
+		{"x/m", Rate{}, true},
+		{"10/week", Rate{}, true},
+		{"10/-1h", Rate{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRate(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRate() = %v, want error: %t", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseRate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	s := NewSemaphore(1)
+
+	release, ok, err := s.Acquire(context.Background())
+	if err != nil || !ok {
+		t.Fatalf("Acquire() = %t, %v, want a slot", ok, err)
+	}
+
+	if _, ok, _ := s.Acquire(context.Background()); ok {
+		t.Fatal("Acquire() returned a slot while none is available")
+	}
+
+	release()
+
+	if _, ok, _ := s.Acquire(context.Background()); !ok {
+		t.Error("Acquire() didn't return the released slot")
+	}
+}
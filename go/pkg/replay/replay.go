@@ -0,0 +1,67 @@
+// Package replay detects requests that were already seen, so that captured
+// credentials can't be used to repeat a request.
+package replay
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store remembers keys of seen requests. Replicas of a producer must share a
+// store for replays across replicas to be detected.
+type Store interface {
+	// Add remembers the key for the provided duration. It returns false if
+	// the key is already remembered. It must be atomic, so that only one of
+	// concurrent calls with the same key succeeds.
+	Add(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Remove forgets the key, so that it can be added again.
+	Remove(ctx context.Context, key string) error
+}
+
+// Memory is an in-memory Store. Its keys are local to a single process.
+type Memory struct {
+	mu   sync.Mutex
+	keys map[string]time.Time
+	now  func() time.Time
+}
+
+// NewMemory creates a new in-memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		keys: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// Add implements Store. Expired keys are removed as new ones are added.
+func (m *Memory) Add(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+
+	if expires, ok := m.keys[key]; ok && now.Before(expires) {
+		return false, nil
+	}
+
+	for k, expires := range m.keys {
+		if !now.Before(expires) {
+			delete(m.keys, k)
+		}
+	}
+
+	m.keys[key] = now.Add(ttl)
+
+	return true, nil
+}
+
+// Remove implements Store.
+func (m *Memory) Remove(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.keys, key)
+
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package schema generates JSON Schemas (draft 2020-12) of Go types, so that
+// producers can publish the structure of their input and payload without
+// maintaining it by hand.
+//
+// Property names are taken from `json` tags, descriptions from `description`
+// tags, and required properties, enums, ranges and patterns from `validate`
+// and `pattern` tags, see package validate.
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/validate"
+)
+
+// Version is the JSON Schema dialect of generated schemas.
+const Version = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema used to describe Go types.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	MaxProperties        *int               `json:"maxProperties,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// For returns the schema of the type of v. It returns nil if v is nil.
+func For(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+
+	s := generate(reflect.TypeOf(v), nil)
+	s.Schema = Version
+
+	return s
+}
+
+// generate returns the schema of t. Types that are already being generated
+// up the stack are recursive, and are described as any value.
+func generate(t reflect.Type, stack []reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// custom JSON encodings can't be described
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+
+		return &Schema{Type: "array", Items: generate(t.Elem(), stack)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: generate(t.Elem(), stack)}
+	case reflect.Struct:
+		if slices.Contains(stack, t) {
+			return &Schema{}
+		}
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		properties(t, append(stack, t), s)
+
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+// properties adds the fields of struct t to s. Fields of embedded structs
+// without a name are added as if they were fields of t, like encoding/json
+// does.
+func properties(t reflect.Type, stack []reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if f.Anonymous && f.Tag.Get("json") == "" && ft.Kind() == reflect.Struct {
+			properties(ft, stack, s)
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		name, ok := validate.FieldName(f)
+		if !ok {
+			continue
+		}
+
+		p := generate(f.Type, stack)
+		p.Description = f.Tag.Get("description")
+
+		s.Properties[name] = p
+
+		for _, rule := range validate.Rules(f) {
+			if rule.Name == validate.RuleRequired {
+				s.Required = append(s.Required, name)
+				continue
+			}
+
+			constrain(p, rule)
+		}
+
+		if pattern := validate.Pattern(f); pattern != "" {
+			// JSON Schema patterns match anywhere in the value, while
+			// validate requires the whole value to match
+			elements(p).Pattern = "^(?:" + pattern + ")$"
+		}
+	}
+}
+
+// constrain adds a validation rule other than required to p.
+func constrain(p *Schema, rule validate.Rule) {
+	switch rule.Name {
+	case validate.RuleEnum:
+		e := elements(p)
+
+		for _, v := range rule.Enum() {
+			e.Enum = append(e.Enum, v)
+		}
+	case validate.RuleMin, validate.RuleMax:
+		n := rule.Number()
+		isMin := rule.Name == validate.RuleMin
+
+		switch p.Type {
+		case "integer", "number":
+			if isMin {
+				p.Minimum = &n
+			} else {
+				p.Maximum = &n
+			}
+		case "string":
+			setLength(&p.MinLength, &p.MaxLength, isMin, n)
+		case "array":
+			setLength(&p.MinItems, &p.MaxItems, isMin, n)
+		case "object":
+			setLength(&p.MinProperties, &p.MaxProperties, isMin, n)
+		}
+	}
+}
+
+// elements returns the schema that enums and patterns apply to: the items of
+// arrays, or p itself.
+func elements(p *Schema) *Schema {
+	if p.Type == "array" && p.Items != nil {
+		return p.Items
+	}
+
+	return p
+}
+
+func setLength(minField **int, maxField **int, isMin bool, n float64) {
+	l := int(n)
+
+	if isMin {
+		*minField = &l
+	} else {
+		*maxField = &l
+	}
+}
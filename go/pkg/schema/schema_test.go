@@ -0,0 +1,77 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Common struct {
+	Region string `json:"region" validate:"required"`
+}
+
+type node struct {
+	Name     string  `json:"name"`
+	Children []*node `json:"children"`
+}
+
+type input struct {
+	Common
+	Domain  string            `json:"domain" validate:"required" pattern:"[a-z.]+" description:"Domain of the certificate"`
+	KeyType string            `json:"key_type,omitempty" validate:"enum=EC256|RSA2048"`
+	Days    *int              `json:"days" validate:"min=1,max=90"`
+	Ratio   float64           `json:"ratio"`
+	Name    string            `json:"name" validate:"min=2,max=4"`
+	Tags    []string          `json:"tags" validate:"max=2,enum=a|b"`
+	Labels  map[string]string `json:"labels" validate:"min=1"`
+	Cert    []byte            `json:"cert"`
+	Expires time.Time         `json:"expires"`
+	Extra   json.RawMessage   `json:"extra"`
+	Tree    node              `json:"tree"`
+	Enabled bool
+	Ignored string `json:"-"`
+	ignored string
+}
+
+func TestFor(t *testing.T) {
+	if s := For(nil); s != nil {
+		t.Errorf("For(nil) = %+v, want nil", s)
+	}
+
+	got := For(&input{})
+
+	one, two, four := 1, 2, 4
+	minDays, maxDays := 1.0, 90.0
+
+	want := &Schema{
+		Schema:   Version,
+		Type:     "object",
+		Required: []string{"region", "domain"},
+		Properties: map[string]*Schema{
+			"region":   {Type: "string"},
+			"domain":   {Type: "string", Description: "Domain of the certificate", Pattern: "^(?:[a-z.]+)$"},
+			"key_type": {Type: "string", Enum: []interface{}{"EC256", "RSA2048"}},
+			"days":     {Type: "integer", Minimum: &minDays, Maximum: &maxDays},
+			"ratio":    {Type: "number"},
+			"name":     {Type: "string", MinLength: &two, MaxLength: &four},
+			"tags":     {Type: "array", Items: &Schema{Type: "string", Enum: []interface{}{"a", "b"}}, MaxItems: &two},
+			"labels":   {Type: "object", AdditionalProperties: &Schema{Type: "string"}, MinProperties: &one},
+			"cert":     {Type: "string", ContentEncoding: "base64"},
+			"expires":  {Type: "string", Format: "date-time"},
+			"extra":    {},
+			"tree": {Type: "object", Properties: map[string]*Schema{
+				"name": {Type: "string"},
+				// recursive types are described as any value
+				"children": {Type: "array", Items: &Schema{}},
+			}},
+			"Enabled": {Type: "boolean"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.MarshalIndent(got, "", "  ")
+		wantJSON, _ := json.MarshalIndent(want, "", "  ")
+		t.Errorf("For() = %s, want %s", gotJSON, wantJSON)
+	}
+}
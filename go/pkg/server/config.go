@@ -0,0 +1,99 @@
+package server
+
+import (
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/config"
+	"github.com/akeylesslabs/custom-producer/go/pkg/sweeper"
+)
+
+// Config is the server section of producer config files, see config.Load.
+// Zero values use the server defaults.
+type Config struct {
+	ListenAddr      []string        `yaml:"listen_addr" toml:"listen_addr" json:"listen_addr" env:"LISTEN_ADDR"`
+	WriteTimeout    config.Duration `yaml:"write_timeout" toml:"write_timeout" json:"write_timeout" env:"WRITE_TIMEOUT"`
+	ShutdownTimeout config.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	TLSCertFile     string          `yaml:"tls_cert_file" toml:"tls_cert_file" json:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile      string          `yaml:"tls_key_file" toml:"tls_key_file" json:"tls_key_file" env:"TLS_KEY_FILE"`
+	// ClientCAFile, AllowedClientNames and AllowedCIDRs restrict access to
+	// every listen address.
+	ClientCAFile       string   `yaml:"client_ca_file" toml:"client_ca_file" json:"client_ca_file" env:"CLIENT_CA_FILE"`
+	AllowedClientNames []string `yaml:"allowed_client_names" toml:"allowed_client_names" json:"allowed_client_names" env:"ALLOWED_CLIENT_NAMES"`
+	AllowedCIDRs       []string `yaml:"allowed_cidrs" toml:"allowed_cidrs" json:"allowed_cidrs" env:"ALLOWED_CIDRS"`
+}
+
+// Options returns the server options of c. The server listens on ":80" if
+// c has no listen address.
+func (c Config) Options() []Option {
+	var opts []Option
+
+	var lopts []ListenerOption
+
+	if c.ClientCAFile != "" {
+		lopts = append(lopts, WithClientCA(c.ClientCAFile), WithAllowedClientNames(c.AllowedClientNames...))
+	}
+
+	if len(c.AllowedCIDRs) > 0 {
+		lopts = append(lopts, WithAllowedCIDRs(c.AllowedCIDRs...))
+	}
+
+	addrs := c.ListenAddr
+	if len(addrs) == 0 {
+		addrs = []string{":80"}
+	}
+
+	for _, addr := range addrs {
+		opts = append(opts, WithListener(addr, lopts...))
+	}
+
+	if d := c.WriteTimeout; d > 0 {
+		opts = append(opts, WithWriteTimeout(time.Duration(d)))
+	}
+
+	if d := c.ShutdownTimeout; d > 0 {
+		opts = append(opts, WithShutdownTimeout(time.Duration(d)))
+	}
+
+	if c.TLSCertFile != "" {
+		opts = append(opts, WithTLSCertificate(c.TLSCertFile, c.TLSKeyFile))
+	}
+
+	return opts
+}
+
+// SweeperConfig is the sweeper section of producer config files. Zero
+// values use the sweeper defaults.
+type SweeperConfig struct {
+	Interval config.Duration `yaml:"interval" toml:"interval" json:"interval" env:"SWEEPER_INTERVAL"`
+	Grace    config.Duration `yaml:"grace" toml:"grace" json:"grace" env:"SWEEPER_GRACE"`
+	// StoreFile keeps credentials across restarts. By default, they are
+	// kept in memory.
+	StoreFile string `yaml:"store_file" toml:"store_file" json:"store_file" env:"SWEEPER_STORE_FILE"`
+}
+
+// NewSweeper creates the sweeper configured by c, which the server runs
+// alongside the producers.
+func NewSweeper(c SweeperConfig) (*sweeper.Sweeper, error) {
+	var store sweeper.Store = sweeper.NewMemory()
+
+	if c.StoreFile != "" {
+		fs, err := sweeper.NewFileStore(c.StoreFile)
+		if err != nil {
+			return nil, err
+		}
+
+		store = fs
+	}
+
+	var opts []sweeper.Option
+
+	if d := c.Interval; d > 0 {
+		opts = append(opts, sweeper.WithInterval(time.Duration(d)))
+	}
+
+	if d := c.Grace; d > 0 {
+		opts = append(opts, sweeper.WithGrace(time.Duration(d)))
+	}
+
+	return sweeper.New(store, opts...), nil
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/config"
+)
+
+func TestConfigOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		wantAddrs []string
+	}{
+		{"default", Config{}, []string{":80"}},
+		{"addresses", Config{ListenAddr: []string{":8080", "unix:/run/producer.sock"}}, []string{":8080", "unix:/run/producer.sock"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.AllowedCIDRs = []string{"10.0.0.0/8"}
+			tt.config.WriteTimeout = config.Duration(time.Minute)
+
+			s := &Server{srv: &http.Server{}}
+			for _, opt := range tt.config.Options() {
+				opt(s)
+			}
+
+			var addrs []string
+			for _, l := range s.listeners {
+				addrs = append(addrs, l.addr)
+
+				// restrictions apply to every address
+				if !slices.Equal(l.cidrs, tt.config.AllowedCIDRs) {
+					t.Errorf("listener %s allows %v, want %v", l.addr, l.cidrs, tt.config.AllowedCIDRs)
+				}
+			}
+
+			if !slices.Equal(addrs, tt.wantAddrs) {
+				t.Errorf("listeners = %v, want %v", addrs, tt.wantAddrs)
+			}
+
+			if s.srv.WriteTimeout != time.Minute {
+				t.Errorf("write timeout = %s, want %s", s.srv.WriteTimeout, time.Minute)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// listener is a single listen address with its own access restrictions.
+type listener struct {
+	addr         string
+	clientCAFile string
+	clientNames  []string
+	cidrs        []string
+
+	prefixes []netip.Prefix
+	tls      *tls.Config
+}
+
+// ListenerOption is a single configuration parameter of a listener.
+type ListenerOption func(*listener)
+
+// WithClientCA requires clients of the listener to present a certificate
+// issued by one of the CAs in the provided PEM file. It requires TLS to be
+// configured.
+func WithClientCA(caFile string) ListenerOption {
+	return func(l *listener) {
+		l.clientCAFile = caFile
+	}
+}
+
+// WithAllowedClientNames pins client certificates of the listener to the
+// provided names. A certificate is accepted if its subject common name, or
+// any of its DNS, URI or email SANs, matches one of the names. It requires
+// WithClientCA.
+func WithAllowedClientNames(names ...string) ListenerOption {
+	return func(l *listener) {
+		l.clientNames = append(l.clientNames, names...)
+	}
+}
+
+// WithAllowedCIDRs restricts the listener to clients connecting from the
+// provided networks, for example, "10.0.0.0/8". Single ip addresses are also
+// accepted. Other connections are closed right after they are accepted,
+// before TLS handshake and before credentials are validated. Not supported
+// for unix sockets.
+func WithAllowedCIDRs(cidrs ...string) ListenerOption {
+	return func(l *listener) {
+		l.cidrs = append(l.cidrs, cidrs...)
+	}
+}
+
+// configure validates the listener and prepares its TLS configuration, based
+// on the server's one.
+func (l *listener) configure(serverTLS *tls.Config) error {
+	for _, cidr := range l.cidrs {
+		if strings.HasPrefix(l.addr, unixPrefix) {
+			return fmt.Errorf("allowed CIDRs aren't supported for %s", l.addr)
+		}
+
+		p, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				return fmt.Errorf("invalid allowed CIDR '%s' of %s: %w", cidr, l.addr, err)
+			}
+
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		l.prefixes = append(l.prefixes, p.Masked())
+	}
+
+	l.tls = serverTLS
+
+	if l.clientCAFile == "" {
+		if len(l.clientNames) > 0 {
+			return fmt.Errorf("allowed client names of %s require a client CA", l.addr)
+		}
+
+		return nil
+	}
+
+	if serverTLS == nil {
+		return fmt.Errorf("client certificates of %s require TLS", l.addr)
+	}
+
+	pem, err := os.ReadFile(l.clientCAFile)
+	if err != nil {
+		return fmt.Errorf("can't read client CA of %s: %w", l.addr, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in client CA file %s", l.clientCAFile)
+	}
+
+	l.tls = serverTLS.Clone()
+	l.tls.ClientAuth = tls.RequireAndVerifyClientCert
+	l.tls.ClientCAs = pool
+
+	if len(l.clientNames) > 0 {
+		l.tls.VerifyConnection = l.verifyClientName
+	}
+
+	return nil
+}
+
+// verifyClientName runs after the client certificate chain is verified, and
+// checks that the certificate belongs to one of the pinned names.
+func (l *listener) verifyClientName(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("client certificate is required")
+	}
+
+	cert := cs.PeerCertificates[0]
+
+	names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+
+	for _, u := range cert.URIs {
+		names = append(names, u.String())
+	}
+
+	for _, name := range names {
+		for _, allowed := range l.clientNames {
+			if name != "" && name == allowed {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("client certificate '%s' isn't allowed", cert.Subject.CommonName)
+}
+
+// wrap applies access restrictions to a raw listener.
+func (l *listener) wrap(nl net.Listener) net.Listener {
+	if len(l.prefixes) > 0 {
+		nl = &cidrListener{Listener: nl, prefixes: l.prefixes}
+	}
+
+	if l.tls != nil {
+		nl = tls.NewListener(nl, l.tls)
+	}
+
+	return nl
+}
+
+// cidrListener closes connections from addresses outside of the allowed
+// networks.
+type cidrListener struct {
+	net.Listener
+	prefixes []netip.Prefix
+}
+
+func (l *cidrListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if l.allowed(conn.RemoteAddr()) {
+			return conn, nil
+		}
+
+		slog.Debug("rejected connection from a disallowed address", "remote_addr", conn.RemoteAddr().String())
+
+		_ = conn.Close()
+	}
+}
+
+func (l *cidrListener) allowed(addr net.Addr) bool {
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return false
+	}
+
+	ip := ap.Addr().Unmap()
+
+	for _, p := range l.prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key := newKey(t)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+// issue returns a certificate for the provided common name, usable by both
+// servers and clients.
+func (ca *testCA) issue(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+
+	key := newKey(t)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+// serve configures l and serves requests on a loopback address with its
+// restrictions applied. It returns the address.
+func serve(t *testing.T, l *listener, serverTLS *tls.Config) string {
+	t.Helper()
+
+	l.addr = "127.0.0.1:0"
+
+	if err := l.configure(serverTLS); err != nil {
+		t.Fatal(err)
+	}
+
+	nl, err := net.Listen("tcp", l.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+		ReadHeaderTimeout: time.Second,
+		// rejected handshakes are expected
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+
+	go func() { _ = srv.Serve(l.wrap(nl)) }()
+
+	t.Cleanup(func() { _ = srv.Close() })
+
+	return nl.Addr().String()
+}
+
+// get sends a request to addr, and fails if it isn't served.
+func get(addr string, clientTLS *tls.Config) error {
+	scheme := "http"
+	if clientTLS != nil {
+		scheme = "https"
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: clientTLS, DisableKeepAlives: true},
+		Timeout:   5 * time.Second,
+	}
+
+	res, err := client.Get(scheme + "://" + addr + "/")
+	if err != nil {
+		return err
+	}
+
+	_ = res.Body.Close()
+
+	return nil
+}
+
+func TestClientCertificates(t *testing.T) {
+	ca := newTestCA(t)
+
+	serverTLS := &tls.Config{Certificates: []tls.Certificate{ca.issue(t, "localhost")}, MinVersion: tls.VersionTLS12}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	clientTLS := func(certs ...tls.Certificate) *tls.Config {
+		return &tls.Config{RootCAs: roots, Certificates: certs, MinVersion: tls.VersionTLS12}
+	}
+
+	other := newTestCA(t)
+
+	tests := []struct {
+		name     string
+		client   *tls.Config
+		accepted bool
+	}{
+		{"allowed name", clientTLS(ca.issue(t, "gateway")), true},
+		{"disallowed name", clientTLS(ca.issue(t, "intruder")), false},
+		{"missing certificate", clientTLS(), false},
+		{"untrusted issuer", clientTLS(other.issue(t, "gateway")), false},
+	}
+
+	addr := serve(t, &listener{clientCAFile: ca.file, clientNames: []string{"gateway"}}, serverTLS)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := get(addr, tt.client)
+
+			if tt.accepted && err != nil {
+				t.Errorf("request was rejected: %v", err)
+			}
+
+			if !tt.accepted && err == nil {
+				t.Error("request was accepted")
+			}
+		})
+	}
+}
+
+func TestAllowedCIDRs(t *testing.T) {
+	tests := []struct {
+		name     string
+		cidrs    []string
+		accepted bool
+	}{
+		{"allowed network", []string{"10.0.0.0/8", "127.0.0.0/8"}, true},
+		{"allowed address", []string{"127.0.0.1"}, true},
+		{"denied", []string{"10.0.0.0/8", "::1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := serve(t, &listener{cidrs: tt.cidrs}, nil)
+
+			err := get(addr, nil)
+
+			if tt.accepted && err != nil {
+				t.Errorf("request was rejected: %v", err)
+			}
+
+			if !tt.accepted && err == nil {
+				t.Error("request was accepted")
+			}
+		})
+	}
+}
+
+func TestListenerConfigErrors(t *testing.T) {
+	ca := newTestCA(t)
+
+	tests := []struct {
+		name string
+		l    *listener
+		tls  *tls.Config
+	}{
+		{"invalid CIDR", &listener{addr: ":0", cidrs: []string{"10.0.0.0/33"}}, nil},
+		{"CIDR of a unix socket", &listener{addr: unixPrefix + "/tmp/s.sock", cidrs: []string{"10.0.0.0/8"}}, nil},
+		{"client names without CA", &listener{addr: ":0", clientNames: []string{"gateway"}}, nil},
+		{"client CA without TLS", &listener{addr: ":0", clientCAFile: ca.file}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.l.configure(tt.tls); err == nil {
+				t.Error("configure() succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import "time"
+
+// Option is a single configuration parameter used by this server.
+type Option func(*Server)
+
+// WithAddress adds a listen address. TCP addresses use "host:port" format,
+// unix sockets use "unix:/path/to/socket" format. May be used more than once
+// to listen on several addresses at the same time.
+func WithAddress(addr string) Option {
+	return WithListener(addr)
+}
+
+// WithListener adds a listen address, like WithAddress, with access
+// restrictions that only apply to this address, such as client certificates
+// and allowed networks.
+func WithListener(addr string, opts ...ListenerOption) Option {
+	return func(s *Server) {
+		l := &listener{addr: addr}
+
+		for _, opt := range opts {
+			opt(l)
+		}
+
+		s.listeners = append(s.listeners, l)
+	}
+}
+
+// WithReadHeaderTimeout sets the time allowed to read request headers.
+func WithReadHeaderTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.srv.ReadHeaderTimeout = d
+	}
+}
+
+// WithReadTimeout sets the time allowed to read the entire request.
+func WithReadTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.srv.ReadTimeout = d
+	}
+}
+
+// WithWriteTimeout sets the time allowed to handle a request and write its
+// response. It must be long enough to complete an entire producer operation,
+// for example, an ACME order.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.srv.WriteTimeout = d
+	}
+}
+
+// WithIdleTimeout sets the time to keep idle keep-alive connections open.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.srv.IdleTimeout = d
+	}
+}
+
+// WithShutdownTimeout sets the time allowed for in-flight requests to
+// complete once shutdown starts. Requests still running after this deadline
+// are interrupted.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = d
+	}
+}
+
+// WithTLSCertificate configures the server to serve HTTPS using the
+// certificate and private key stored in the provided PEM files.
+func WithTLSCertificate(certFile, keyFile string) Option {
+	return func(s *Server) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// WithSelfIssuedCertificate configures the server to serve HTTPS using a
+// certificate generated at startup for the provided host names and ip
+// addresses. Useful when TLS is terminated by a proxy that doesn't verify
+// upstream certificates, but the traffic between them must be encrypted.
+func WithSelfIssuedCertificate(hosts ...string) Option {
+	return func(s *Server) {
+		s.selfIssued = true
+		s.selfIssuedHosts = hosts
+	}
+}
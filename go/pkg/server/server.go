@@ -0,0 +1,160 @@
+// Package server runs HTTP handlers of custom producers with production
+// defaults: server timeouts, multiple listeners (TCP and unix sockets),
+// optional TLS and graceful shutdown.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const unixPrefix = "unix:"
+
+// Server wraps http.Server.
+type Server struct {
+	srv             *http.Server
+	listeners       []*listener
+	shutdownTimeout time.Duration
+
+	certFile        string
+	keyFile         string
+	selfIssued      bool
+	selfIssuedHosts []string
+}
+
+// New creates a new server for the provided handler. Unless configured
+// otherwise, it listens on ":80".
+func New(h http.Handler, opts ...Option) (*Server, error) {
+	s := &Server{
+		srv: &http.Server{
+			Handler:           h,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      5 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+		},
+		shutdownTimeout: 5 * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if len(s.listeners) == 0 {
+		s.listeners = []*listener{{addr: ":80"}}
+	}
+
+	switch {
+	case s.certFile != "" || s.keyFile != "":
+		cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load tls certificate: %w", err)
+		}
+
+		s.srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	case s.selfIssued:
+		cert, err := selfIssuedCertificate(s.selfIssuedHosts)
+		if err != nil {
+			return nil, fmt.Errorf("can't issue tls certificate: %w", err)
+		}
+
+		s.srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+
+	for _, l := range s.listeners {
+		if err := l.configure(s.srv.TLSConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Run starts serving requests on every configured address, and blocks until
+// the provided context is done or one of the listeners fails. Once the
+// context is done, the server stops accepting new connections and waits for
+// in-flight requests to complete, up to the configured shutdown timeout.
+func (s *Server) Run(ctx context.Context) error {
+	listeners := make([]net.Listener, 0, len(s.listeners))
+
+	for _, lc := range s.listeners {
+		l, err := listen(lc.addr)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+
+			return err
+		}
+
+		slog.Info("listening", "address", lc.addr, "client_ca", lc.clientCAFile != "", "allowed_cidrs", lc.cidrs)
+
+		listeners = append(listeners, lc.wrap(l))
+	}
+
+	errCh := make(chan error, len(listeners))
+
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errCh <- s.srv.Serve(l)
+		}(l)
+	}
+
+	select {
+	case err := <-errCh:
+		_ = s.srv.Close()
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, waiting for in-flight requests", "timeout", s.shutdownTimeout.String())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	if err := s.srv.Shutdown(shutdownCtx); err != nil {
+		_ = s.srv.Close()
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+
+	for range listeners {
+		if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("server failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func listen(addr string) (net.Listener, error) {
+	if !strings.HasPrefix(addr, unixPrefix) {
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("can't listen on %s: %w", addr, err)
+		}
+
+		return l, nil
+	}
+
+	path := strings.TrimPrefix(addr, unixPrefix)
+
+	// a socket file left behind by a previous process prevents listening
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("can't remove stale socket %s: %w", path, err)
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen on %s: %w", addr, err)
+	}
+
+	return l, nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows && !plan9
+
+package sweeper
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock of f, which is released when f is closed.
+// It fails right away if another process holds the lock.
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
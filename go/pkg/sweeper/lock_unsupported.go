@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package sweeper
+
+import "os"
+
+// lockFile does nothing, since files aren't locked on this platform.
+func lockFile(*os.File) error {
+	return nil
+}
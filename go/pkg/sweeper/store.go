@@ -0,0 +1,197 @@
+package sweeper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+type key struct {
+	producer string
+	id       string
+}
+
+// Memory is an in-memory Store. Registered credentials are lost on restart.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[key]Entry
+}
+
+// NewMemory creates a new in-memory store.
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[key]Entry)}
+}
+
+// Add implements Store.
+func (m *Memory) Add(_ context.Context, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[key{e.Producer, e.ID}] = e
+
+	return nil
+}
+
+// Update implements Store.
+func (m *Memory) Update(_ context.Context, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key{e.Producer, e.ID}
+	if _, ok := m.entries[k]; ok {
+		m.entries[k] = e
+	}
+
+	return nil
+}
+
+// Remove implements Store.
+func (m *Memory) Remove(_ context.Context, producer string, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range ids {
+		delete(m.entries, key{producer, id})
+	}
+
+	return nil
+}
+
+// Expired implements Store.
+func (m *Memory) Expired(_ context.Context, before time.Time, after *Entry, limit int) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []Entry
+
+	for _, e := range m.entries {
+		if e.Expires.Before(before) && (after == nil || after.less(e)) {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].less(entries[j]) })
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries, nil
+}
+
+// FileStore is a Store that keeps credentials in a JSON file, so that they
+// survive restarts of a single replica. The file is rewritten on every change
+// and is only readable by its owner.
+//
+// Payloads are stored as received: they are plain text, including any admin
+// credentials, unless the webhook requires encrypted payloads, see
+// webhook.WithPayloadKeys. The bundled commands refuse to use a file store
+// without payload keys.
+//
+// While the store is open, the file is locked, so that a second process
+// sharing it fails to open it instead of revoking the same credentials.
+type FileStore struct {
+	*Memory
+	path string
+	lock *os.File
+
+	// saveMu serializes saves, so that an older snapshot never overwrites
+	// a newer one
+	saveMu sync.Mutex
+}
+
+// NewFileStore opens (or creates) the store in the provided file. It fails if
+// another process has the store open.
+func NewFileStore(path string) (*FileStore, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("can't open sweeper store lock: %w", err)
+	}
+
+	if err := lockFile(lock); err != nil {
+		_ = lock.Close()
+		return nil, fmt.Errorf("sweeper store %s is used by another process: %w", path, err)
+	}
+
+	s := &FileStore{Memory: NewMemory(), path: path, lock: lock}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		_ = s.Close()
+		return nil, fmt.Errorf("can't read sweeper store: %w", err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		_ = s.Close()
+		return nil, fmt.Errorf("can't parse sweeper store %s: %w", path, err)
+	}
+
+	for _, e := range entries {
+		s.entries[key{e.Producer, e.ID}] = e
+	}
+
+	return s, nil
+}
+
+// Close releases the lock of the store, so that another process may open it.
+func (s *FileStore) Close() error {
+	return s.lock.Close()
+}
+
+// Add implements Store.
+func (s *FileStore) Add(ctx context.Context, e Entry) error {
+	_ = s.Memory.Add(ctx, e)
+	return s.save()
+}
+
+// Update implements Store.
+func (s *FileStore) Update(ctx context.Context, e Entry) error {
+	_ = s.Memory.Update(ctx, e)
+	return s.save()
+}
+
+// Remove implements Store.
+func (s *FileStore) Remove(ctx context.Context, producer string, ids ...string) error {
+	_ = s.Memory.Remove(ctx, producer, ids...)
+	return s.save()
+}
+
+// save writes every entry to a temporary file and renames it, so that a
+// crash never leaves a partially written store.
+func (s *FileStore) save() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+
+	s.mu.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("can't marshal sweeper store: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("can't write sweeper store: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("can't write sweeper store: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+// Package sweeper revokes credentials that were never revoked by Akeyless,
+// for example, because the gateway was down or the producer item was deleted.
+// Every created credential is registered with an expiry, and a background
+// loop revokes credentials past their expiry and a grace period.
+package sweeper
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of sweeps, as reported in metric labels.
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+	outcomeUnknown = "unknown_producer"
+)
+
+const (
+	defaultInterval = time.Minute
+	defaultGrace    = 10 * time.Minute
+	defaultBatch    = 100
+
+	// maxBackoff limits the delay between attempts to revoke a credential.
+	maxBackoff = 24 * time.Hour
+)
+
+var (
+	orphaned = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "akeyless_producer",
+		Subsystem: "sweeper",
+		Name:      "orphaned_credentials",
+		Help:      "Number of credentials past their expiry and grace period that are still not revoked, as of the last sweep.",
+	}, []string{"producer"})
+
+	swept = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "akeyless_producer",
+		Subsystem: "sweeper",
+		Name:      "revocations_total",
+		Help:      "Number of revocations of orphaned credentials by outcome.",
+	}, []string{"producer", "outcome"})
+)
+
+// Entry is a single registered credential.
+type Entry struct {
+	// Producer is the name of the producer that created the credential.
+	Producer string `json:"producer"`
+	// ID is the ID returned by create operation.
+	ID string `json:"id"`
+	// Payload is the producer payload of the create request, as received.
+	// It usually includes admin credentials, so stores must protect it.
+	Payload string `json:"payload"`
+	// Expires is the time the credential should have been revoked by.
+	Expires time.Time `json:"expires"`
+	// Attempts is the number of failed attempts to revoke the credential.
+	Attempts int `json:"attempts,omitempty"`
+	// NextAttempt is the time before which the credential isn't revoked
+	// again, after a failed attempt.
+	NextAttempt time.Time `json:"next_attempt,omitempty"`
+}
+
+// less orders entries by expiry, then by producer and ID, which is the order
+// Store.Expired returns them in.
+func (e Entry) less(other Entry) bool {
+	if !e.Expires.Equal(other.Expires) {
+		return e.Expires.Before(other.Expires)
+	}
+
+	if e.Producer != other.Producer {
+		return e.Producer < other.Producer
+	}
+
+	return e.ID < other.ID
+}
+
+// Store keeps registered credentials. Stores shipped with this package are
+// local to a single process, and the sweeper doesn't coordinate with other
+// replicas, so a store must not be shared by several of them.
+type Store interface {
+	// Add registers a credential, replacing any entry with the same
+	// producer and ID.
+	Add(ctx context.Context, e Entry) error
+	// Update replaces the entry with the same producer and ID, if it is
+	// still registered. Credentials removed in the meantime, for example,
+	// revoked by Akeyless during a sweep, aren't registered again.
+	Update(ctx context.Context, e Entry) error
+	// Remove forgets credentials of the producer, once they are revoked.
+	Remove(ctx context.Context, producer string, ids ...string) error
+	// Expired returns up to limit credentials that expired before the
+	// provided time, ordered by expiry, then by producer and ID. The page
+	// starts right after the provided entry, or from the first credential
+	// if it is nil.
+	Expired(ctx context.Context, before time.Time, after *Entry, limit int) ([]Entry, error)
+}
+
+// RevokeFunc revokes a single expired credential.
+type RevokeFunc func(ctx context.Context, e Entry) error
+
+// Option is a single configuration parameter used by Sweeper.
+type Option func(*Sweeper)
+
+// WithInterval sets how often expired credentials are swept. The default is
+// one minute.
+func WithInterval(d time.Duration) Option {
+	return func(s *Sweeper) {
+		s.interval = d
+	}
+}
+
+// WithGrace sets the time after expiry during which Akeyless is still
+// expected to revoke the credential itself. The default is 10 minutes.
+func WithGrace(d time.Duration) Option {
+	return func(s *Sweeper) {
+		s.grace = d
+	}
+}
+
+// Sweeper registers created credentials and revokes expired ones.
+type Sweeper struct {
+	store    Store
+	interval time.Duration
+	grace    time.Duration
+
+	mu       sync.RWMutex
+	revokers map[string]RevokeFunc
+}
+
+// New creates a new sweeper that keeps credentials in the provided store.
+func New(store Store, opts ...Option) *Sweeper {
+	s := &Sweeper{
+		store:    store,
+		interval: defaultInterval,
+		grace:    defaultGrace,
+		revokers: make(map[string]RevokeFunc),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Handle sets the function that revokes credentials of the named producer.
+func (s *Sweeper) Handle(producer string, revoke RevokeFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokers[producer] = revoke
+}
+
+// Register records a created credential.
+func (s *Sweeper) Register(ctx context.Context, e Entry) error {
+	return s.store.Add(ctx, e)
+}
+
+// Revoked forgets credentials that were revoked.
+func (s *Sweeper) Revoked(ctx context.Context, producer string, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return s.store.Remove(ctx, producer, ids...)
+}
+
+// Run sweeps expired credentials periodically until ctx is done.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep(ctx)
+		}
+	}
+}
+
+// Sweep revokes credentials past their expiry and grace period once.
+func (s *Sweeper) Sweep(ctx context.Context) {
+	now := time.Now()
+	counts := make(map[string]int)
+
+	// every expired credential is visited, so that credentials that keep
+	// failing don't prevent revoking the others
+	var after *Entry
+
+	for {
+		entries, err := s.store.Expired(ctx, now.Add(-s.grace), after, defaultBatch)
+		if err != nil {
+			slog.Error("can't list expired credentials", "error", err)
+			return
+		}
+
+		for _, e := range entries {
+			if !s.sweep(ctx, e, now) {
+				counts[e.Producer]++
+			}
+		}
+
+		if len(entries) < defaultBatch {
+			break
+		}
+
+		after = &entries[len(entries)-1]
+	}
+
+	orphaned.Reset()
+
+	for producer, n := range counts {
+		orphaned.WithLabelValues(producer).Set(float64(n))
+	}
+}
+
+// sweep revokes a single expired credential, unless it is backing off after
+// a failed attempt. It reports whether the credential was revoked.
+func (s *Sweeper) sweep(ctx context.Context, e Entry, now time.Time) bool {
+	if now.Before(e.NextAttempt) {
+		return false
+	}
+
+	s.mu.RLock()
+	revoke, ok := s.revokers[e.Producer]
+	s.mu.RUnlock()
+
+	if !ok {
+		// the producer may be registered again, for example, by a
+		// reload of a multiplexer
+		swept.WithLabelValues(e.Producer, outcomeUnknown).Inc()
+		s.backoff(ctx, e, now)
+
+		return false
+	}
+
+	if err := revoke(ctx, e); err != nil {
+		swept.WithLabelValues(e.Producer, outcomeFailure).Inc()
+		slog.Error("failed to revoke orphaned credential", "producer", e.Producer, "id", e.ID, "attempts", e.Attempts+1, "error", err)
+		s.backoff(ctx, e, now)
+
+		return false
+	}
+
+	swept.WithLabelValues(e.Producer, outcomeSuccess).Inc()
+	slog.Warn("revoked orphaned credential", "producer", e.Producer, "id", e.ID, "expired", e.Expires)
+
+	if err := s.store.Remove(ctx, e.Producer, e.ID); err != nil {
+		slog.Error("can't remove revoked credential", "producer", e.Producer, "id", e.ID, "error", err)
+	}
+
+	return true
+}
+
+// backoff delays the next attempt to revoke a credential. The delay starts
+// at the sweep interval and doubles with every failed attempt, up to a day.
+func (s *Sweeper) backoff(ctx context.Context, e Entry, now time.Time) {
+	delay := s.interval
+
+	for i := 0; i < e.Attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	e.Attempts++
+	e.NextAttempt = now.Add(delay)
+
+	if err := s.store.Update(ctx, e); err != nil {
+		slog.Error("can't update orphaned credential", "producer", e.Producer, "id", e.ID, "error", err)
+	}
+}
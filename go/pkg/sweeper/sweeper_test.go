@@ -0,0 +1,237 @@
+package sweeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// revoker records revoked IDs, and fails to revoke IDs of failing.
+type revoker struct {
+	mu      sync.Mutex
+	revoked []string
+	calls   map[string]int
+	failing map[string]bool
+}
+
+func (r *revoker) revoke(_ context.Context, e Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.calls == nil {
+		r.calls = make(map[string]int)
+	}
+
+	r.calls[e.ID]++
+
+	if r.failing[e.ID] {
+		return errors.New("revoke failed")
+	}
+
+	r.revoked = append(r.revoked, e.ID)
+
+	return nil
+}
+
+func TestSweepPagesPastStuckCredentials(t *testing.T) {
+	store := NewMemory()
+	expired := time.Now().Add(-time.Hour)
+
+	r := &revoker{failing: make(map[string]bool)}
+
+	// the oldest credentials fill more than a batch, and never revoke
+	for i := 0; i < defaultBatch+10; i++ {
+		id := fmt.Sprintf("stuck-%03d", i)
+		r.failing[id] = true
+		_ = store.Add(context.Background(), Entry{Producer: "p", ID: id, Expires: expired.Add(-time.Duration(i) * time.Second)})
+	}
+
+	for i := 0; i < 5; i++ {
+		_ = store.Add(context.Background(), Entry{Producer: "gone", ID: fmt.Sprintf("unknown-%d", i), Expires: expired})
+	}
+
+	_ = store.Add(context.Background(), Entry{Producer: "p", ID: "newer", Expires: expired.Add(time.Minute)})
+
+	s := New(store, WithGrace(0))
+	s.Handle("p", r.revoke)
+
+	s.Sweep(context.Background())
+
+	if len(r.revoked) != 1 || r.revoked[0] != "newer" {
+		t.Fatalf("revoked %v, want [newer]", r.revoked)
+	}
+
+	if got := gauge(t, "p"); got != defaultBatch+10 {
+		t.Errorf("orphaned credentials of p = %v, want %d", got, defaultBatch+10)
+	}
+
+	if got := gauge(t, "gone"); got != 5 {
+		t.Errorf("orphaned credentials of an unknown producer = %v, want 5", got)
+	}
+
+	// failed credentials back off, instead of being retried every sweep
+	s.Sweep(context.Background())
+
+	if n := r.calls["stuck-000"]; n != 1 {
+		t.Errorf("failing credential was revoked %d times, want 1", n)
+	}
+
+	entries, _ := store.Expired(context.Background(), time.Now(), nil, 1000)
+	for _, e := range entries {
+		if e.Attempts != 1 || !e.NextAttempt.After(time.Now()) {
+			t.Errorf("credential %s has %d attempts, next at %v, want a backoff", e.ID, e.Attempts, e.NextAttempt)
+		}
+	}
+}
+
+// gauge returns the number of orphaned credentials of the producer.
+func gauge(t *testing.T, producer string) float64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := orphaned.WithLabelValues(producer).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+
+	return m.GetGauge().GetValue()
+}
+
+func TestBackoff(t *testing.T) {
+	store := NewMemory()
+	s := New(store, WithInterval(time.Minute))
+	now := time.Now()
+
+	_ = store.Add(context.Background(), Entry{Producer: "p", ID: "id"})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{3, 8 * time.Minute},
+		{100, maxBackoff},
+	}
+
+	for _, tt := range tests {
+		s.backoff(context.Background(), Entry{Producer: "p", ID: "id", Attempts: tt.attempts}, now)
+
+		entries, _ := store.Expired(context.Background(), now.Add(time.Hour), nil, 1)
+		if got := entries[0].NextAttempt.Sub(now); got != tt.want {
+			t.Errorf("backoff after %d attempts = %v, want %v", tt.attempts, got, tt.want)
+		}
+
+		if entries[0].Attempts != tt.attempts+1 {
+			t.Errorf("attempts = %d, want %d", entries[0].Attempts, tt.attempts+1)
+		}
+	}
+}
+
+func TestBackoffAfterRevoke(t *testing.T) {
+	store := NewMemory()
+	e := Entry{Producer: "p", ID: "id", Expires: time.Now().Add(-time.Hour)}
+	_ = store.Add(context.Background(), e)
+
+	s := New(store, WithGrace(0))
+
+	// Akeyless revokes the credential while the sweeper fails to
+	s.Handle("p", func(ctx context.Context, e Entry) error {
+		_ = s.Revoked(ctx, e.Producer, e.ID)
+		return errors.New("revoke failed")
+	})
+
+	s.Sweep(context.Background())
+
+	if entries, _ := store.Expired(context.Background(), time.Now(), nil, 10); len(entries) != 0 {
+		t.Errorf("revoked credential was registered again: %+v", entries)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sweeper.json")
+	expires := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	fs, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore() failed: %v", err)
+	}
+
+	_ = fs.Add(context.Background(), Entry{Producer: "p", ID: "a", Expires: expires})
+	_ = fs.Add(context.Background(), Entry{Producer: "p", ID: "b", Expires: expires})
+	_ = fs.Remove(context.Background(), "p", "b")
+	_ = fs.Update(context.Background(), Entry{Producer: "p", ID: "a", Expires: expires, Attempts: 1})
+	_ = fs.Update(context.Background(), Entry{Producer: "p", ID: "b", Expires: expires, Attempts: 1})
+
+	// another replica can't use the same file
+	if _, err := NewFileStore(path); err == nil {
+		t.Fatal("NewFileStore() of a file in use succeeded")
+	}
+
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore() after Close failed: %v", err)
+	}
+
+	t.Cleanup(func() { _ = reopened.Close() })
+
+	entries, _ := reopened.Expired(context.Background(), time.Now(), nil, 10)
+	if len(entries) != 1 || entries[0].ID != "a" || entries[0].Attempts != 1 || !entries[0].Expires.Equal(expires) {
+		t.Errorf("reopened store has %+v, want only the updated entry a", entries)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("store file mode is %v, want 0600", mode)
+	}
+}
+
+func TestExpiredPages(t *testing.T) {
+	store := NewMemory()
+	expires := time.Now().Add(-time.Hour)
+
+	// entries with the same expiry are ordered by producer and ID
+	for _, id := range []string{"c", "a", "b", "d"} {
+		_ = store.Add(context.Background(), Entry{Producer: "p", ID: id, Expires: expires})
+	}
+
+	var (
+		got   []string
+		after *Entry
+	)
+
+	for {
+		page, err := store.Expired(context.Background(), time.Now(), after, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, e := range page {
+			got = append(got, e.ID)
+		}
+
+		if len(page) < 3 {
+			break
+		}
+
+		after = &page[len(page)-1]
+	}
+
+	if fmt.Sprint(got) != "[a b c d]" {
+		t.Errorf("pages returned %v, want [a b c d]", got)
+	}
+}
@@ -0,0 +1,125 @@
+// Package tracing configures OpenTelemetry tracing for producer binaries.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported exporters.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Option is a single tracing configuration parameter.
+type Option func(*options)
+
+type options struct {
+	exporter     string
+	spanExporter sdktrace.SpanExporter
+	serviceName  string
+}
+
+// WithExporter selects one of the supported exporters by name. OTLP exporter
+// is configured using standard OTEL_EXPORTER_OTLP_* environment variables.
+func WithExporter(name string) Option {
+	return func(o *options) {
+		o.exporter = name
+	}
+}
+
+// WithSpanExporter configures tracing to use the provided exporter instead of
+// a named one, for example, tracetest.InMemoryExporter in tests.
+func WithSpanExporter(exp sdktrace.SpanExporter) Option {
+	return func(o *options) {
+		o.spanExporter = exp
+	}
+}
+
+// WithServiceName sets the service name reported with every span.
+func WithServiceName(name string) Option {
+	return func(o *options) {
+		o.serviceName = name
+	}
+}
+
+// Setup configures global tracer provider and W3C trace context propagation.
+// The returned function flushes pending spans and must be called before the
+// process exits. With no exporter configured, spans are not recorded.
+func Setup(ctx context.Context, opts ...Option) (func(context.Context) error, error) {
+	o := &options{exporter: ExporterNone, serviceName: "akeyless-custom-producer"}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exp := o.spanExporter
+
+	if exp == nil {
+		var err error
+
+		switch o.exporter {
+		case ExporterNone, "":
+			return func(context.Context) error { return nil }, nil
+		case ExporterOTLP:
+			exp, err = otlptracehttp.New(ctx)
+		case ExporterStdout:
+			exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		default:
+			return nil, fmt.Errorf("unknown trace exporter '%s'", o.exporter)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("can't create %s trace exporter: %w", o.exporter, err)
+		}
+	}
+
+	// standard OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES environment
+	// variables take precedence over the configured service name
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceName(o.serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't create trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// End records the provided error (if any) on the span, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
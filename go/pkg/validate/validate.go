@@ -0,0 +1,468 @@
+// Package validate checks struct values against rules declared in their
+// `validate` struct tags, and reports violations using JSON pointers (RFC
+// 6901) built from `json` tags, so that callers can find the offending field
+// in their request.
+//
+// Rules are separated by commas:
+//
+//	required    the field must not be empty
+//	enum=a|b    strings must be one of the listed values
+//	min=N       numbers must be at least N, strings, slices and maps must
+//	            have at least N characters or elements
+//	max=N       same as min, but at most N
+//
+// Since regular expressions often include commas, patterns are declared in
+// their own `pattern` struct tag, in RE2 syntax. The whole value must match.
+//
+// Enums and patterns of slices apply to each element. Rules other than
+// required are skipped for empty values, which usually mean that an optional
+// field was omitted. Use pointers to check zero numbers.
+//
+//	type Input struct {
+//		Domain  string `json:"domain" validate:"required" pattern:"[a-z0-9.-]+"`
+//		KeyType string `json:"key_type" validate:"enum=EC256|RSA2048"`
+//		Days    *int   `json:"days" validate:"min=1,max=90"`
+//	}
+//
+// Invalid rules are programming errors: Check reports them, so that programs
+// can refuse to start, and Struct panics on them.
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Names of supported rules.
+const (
+	RuleRequired = "required"
+	RuleEnum     = "enum"
+	RuleMin      = "min"
+	RuleMax      = "max"
+)
+
+// Violation is a single failed rule.
+type Violation struct {
+	// Pointer is a JSON pointer to the field, for example, "/domain".
+	Pointer string `json:"pointer"`
+	// Message describes the violation.
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Pointer, v.Message)
+}
+
+// Rule is a single rule of a `validate` struct tag.
+type Rule struct {
+	// Name is one of the supported rules, for example, "enum".
+	Name string
+	// Arg is the text after "=", if any, for example, "a|b".
+	Arg string
+}
+
+// Enum returns the allowed values of an enum rule.
+func (r Rule) Enum() []string {
+	return strings.Split(r.Arg, "|")
+}
+
+// Number returns the argument of a min or max rule.
+func (r Rule) Number() float64 {
+	n, err := strconv.ParseFloat(r.Arg, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validate: invalid %s rule argument '%s'", r.Name, r.Arg))
+	}
+
+	return n
+}
+
+// Check reports invalid rules declared in the struct tags of t, and of the
+// structs, slices and maps it contains: unknown rules, min and max arguments
+// that aren't numbers, patterns that don't compile, and rules that don't
+// apply to the type of their field.
+func Check(t reflect.Type) error {
+	return checkType(t, make(map[reflect.Type]bool))
+}
+
+func checkType(t reflect.Type, seen map[reflect.Type]bool) error {
+	t = indirectType(t)
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if seen[t] {
+			return nil
+		}
+
+		seen[t] = true
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			if _, ok := FieldName(f); !ok {
+				continue
+			}
+
+			if err := checkField(f); err != nil {
+				return fmt.Errorf("validate: invalid rules of %s.%s: %w", t, f.Name, err)
+			}
+
+			if err := checkType(f.Type, seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return checkType(t.Elem(), seen)
+	}
+
+	return nil
+}
+
+func checkField(f reflect.StructField) error {
+	for _, rule := range Rules(f) {
+		switch rule.Name {
+		case RuleRequired:
+		case RuleEnum:
+			if !isStrings(f.Type) {
+				return fmt.Errorf("%s rule doesn't apply to %s", rule.Name, f.Type)
+			}
+		case RuleMin, RuleMax:
+			if _, err := strconv.ParseFloat(rule.Arg, 64); err != nil {
+				return fmt.Errorf("invalid %s rule argument '%s'", rule.Name, rule.Arg)
+			}
+
+			if _, _, ok := size(reflect.Zero(indirectType(f.Type))); !ok {
+				return fmt.Errorf("%s rule doesn't apply to %s", rule.Name, f.Type)
+			}
+		default:
+			return fmt.Errorf("unknown rule '%s'", rule.Name)
+		}
+	}
+
+	if p := Pattern(f); p != "" {
+		if _, err := compilePattern(p); err != nil {
+			return err
+		}
+
+		if !isStrings(f.Type) {
+			return fmt.Errorf("pattern doesn't apply to %s", f.Type)
+		}
+	}
+
+	return nil
+}
+
+// isStrings reports whether enums and patterns apply to t: strings, and
+// slices of strings.
+func isStrings(t reflect.Type) bool {
+	t = indirectType(t)
+
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = indirectType(t.Elem())
+	}
+
+	return t.Kind() == reflect.String
+}
+
+// Struct validates v, which must be a struct or a pointer to one. Nested
+// structs, and slices and maps of structs, are validated too.
+func Struct(v interface{}) []Violation {
+	var violations []Violation
+
+	walk(reflect.ValueOf(v), "", &violations)
+
+	return violations
+}
+
+// DecodeError describes an error returned by encoding/json as a violation,
+// without quoting the decoded document, since it may include secrets.
+func DecodeError(err error) Violation {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return Violation{
+			Pointer: "/" + strings.ReplaceAll(typeErr.Field, ".", "/"),
+			Message: "must be " + typeErr.Type.String(),
+		}
+	}
+
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return Violation{Pointer: "/" + strings.Trim(name, `"`), Message: "unknown field"}
+	}
+
+	return Violation{Pointer: "", Message: "must be a JSON object"}
+}
+
+func walk(v reflect.Value, pointer string, violations *[]Violation) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			name, ok := FieldName(f)
+			if !ok {
+				continue
+			}
+
+			fv := v.Field(i)
+			fp := pointer + "/" + escape(name)
+
+			for _, rule := range Rules(f) {
+				*violations = append(*violations, check(rule, fv, fp)...)
+			}
+
+			if p := Pattern(f); p != "" {
+				*violations = append(*violations, checkPattern(p, fv, fp)...)
+			}
+
+			walk(fv, fp, violations)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walk(v.Index(i), pointer+"/"+strconv.Itoa(i), violations)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			walk(iter.Value(), pointer+"/"+escape(fmt.Sprint(iter.Key().Interface())), violations)
+		}
+	}
+}
+
+// FieldName returns the JSON name of a struct field. Fields that aren't
+// marshaled return false.
+func FieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+
+	return f.Name, true
+}
+
+// Rules returns the rules declared in the `validate` tag of a struct field.
+func Rules(f reflect.StructField) []Rule {
+	tag := f.Tag.Get("validate")
+	if tag == "" {
+		return nil
+	}
+
+	var rules []Rule
+
+	for _, s := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(s, "=")
+		rules = append(rules, Rule{Name: name, Arg: arg})
+	}
+
+	return rules
+}
+
+// Pattern returns the regular expression declared in the `pattern` tag of a
+// struct field.
+func Pattern(f reflect.StructField) string {
+	return f.Tag.Get("pattern")
+}
+
+func check(rule Rule, v reflect.Value, pointer string) []Violation {
+	if rule.Name == RuleRequired {
+		if isEmpty(v) {
+			return []Violation{{Pointer: pointer, Message: "is required"}}
+		}
+
+		return nil
+	}
+
+	if v = optional(v); !v.IsValid() {
+		return nil
+	}
+
+	switch rule.Name {
+	case RuleEnum:
+		allowed := rule.Enum()
+
+		return eachString(v, pointer, func(s string) string {
+			for _, a := range allowed {
+				if s == a {
+					return ""
+				}
+			}
+
+			return "must be one of " + strings.Join(allowed, ", ")
+		})
+	case RuleMin, RuleMax:
+		n, unit, ok := size(v)
+		if !ok {
+			panic(fmt.Sprintf("validate: %s rule doesn't apply to %s", rule.Name, v.Type()))
+		}
+
+		limit := rule.Number()
+
+		if rule.Name == RuleMin && n < limit {
+			return []Violation{{Pointer: pointer, Message: "must be at least " + rule.Arg + unit}}
+		}
+
+		if rule.Name == RuleMax && n > limit {
+			return []Violation{{Pointer: pointer, Message: "must be at most " + rule.Arg + unit}}
+		}
+
+		return nil
+	default:
+		panic(fmt.Sprintf("validate: unknown rule '%s'", rule.Name))
+	}
+}
+
+func checkPattern(pattern string, v reflect.Value, pointer string) []Violation {
+	if v = optional(v); !v.IsValid() {
+		return nil
+	}
+
+	re := compile(pattern)
+
+	return eachString(v, pointer, func(s string) string {
+		if re.MatchString(s) {
+			return ""
+		}
+
+		return "must match pattern " + pattern
+	})
+}
+
+// eachString checks a string, or each element of a slice of strings.
+func eachString(v reflect.Value, pointer string, check func(string) string) []Violation {
+	var violations []Violation
+
+	switch v.Kind() {
+	case reflect.String:
+		if msg := check(v.String()); msg != "" {
+			violations = append(violations, Violation{Pointer: pointer, Message: msg})
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			violations = append(violations, eachString(indirect(v.Index(i)), pointer+"/"+strconv.Itoa(i), check)...)
+		}
+	default:
+		panic(fmt.Sprintf("validate: enum and pattern rules don't apply to %s", v.Type()))
+	}
+
+	return violations
+}
+
+// size returns the value of a number, or the length of a string, slice or
+// map, and the unit to report it in.
+func size(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters long", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " elements long", true
+	default:
+		return 0, "", false
+	}
+}
+
+func isEmpty(v reflect.Value) bool {
+	if !v.IsValid() || v.IsZero() {
+		return true
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	default:
+		return false
+	}
+}
+
+// optional returns the value to check rules other than required against, or
+// an invalid value if the field was omitted: if it is nil, or empty and not
+// a pointer.
+func optional(v reflect.Value) reflect.Value {
+	pointer := v.Kind() == reflect.Pointer
+
+	if v = indirect(v); !v.IsValid() || !pointer && isEmpty(v) {
+		return reflect.Value{}
+	}
+
+	return v
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+
+		v = v.Elem()
+	}
+
+	return v
+}
+
+var patterns sync.Map
+
+// compile returns the compiled pattern, anchored so that the whole value
+// must match.
+func compile(pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+
+	re, err := compilePattern(pattern)
+	if err != nil {
+		panic("validate: " + err.Error())
+	}
+
+	patterns.Store(pattern, re)
+
+	return re
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+
+	return re, nil
+}
+
+// escape encodes a JSON pointer reference token.
+func escape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
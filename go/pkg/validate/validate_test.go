@@ -0,0 +1,187 @@
+package validate
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type input struct {
+	Domain   string             `json:"domain" validate:"required" pattern:"[a-z0-9.-]+"`
+	KeyType  string             `json:"key_type,omitempty" validate:"enum=EC256|RSA2048"`
+	Days     *int               `json:"days" validate:"min=1,max=90"`
+	Ratio    float64            `json:"ratio" validate:"max=0.5"`
+	Name     string             `json:"name" validate:"min=2,max=4"`
+	Tags     []string           `json:"tags" validate:"max=2,enum=a|b" pattern:"[ab]"`
+	Labels   map[string]string  `json:"labels" validate:"min=1"`
+	Address  *address           `json:"address"`
+	Others   []address          `json:"others"`
+	ByName   map[string]address `json:"by_name"`
+	Ignored  string             `json:"-" validate:"required"`
+	Untagged string             `validate:"required"`
+	ignored  string             `validate:"required"`
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestStruct(t *testing.T) {
+	valid := func() input {
+		return input{Domain: "example.com", Untagged: "u"}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*input)
+		want   []Violation
+	}{
+		{"valid", func(*input) {}, nil},
+		{"valid values", func(in *input) {
+			in.KeyType = "EC256"
+			in.Days = intPtr(90)
+			in.Ratio = 0.5
+			// lengths are counted in characters, not bytes
+			in.Name = "héll"
+			in.Tags = []string{"a", "b"}
+			in.Labels = map[string]string{"k": "v"}
+			in.Address = &address{City: "Tel Aviv"}
+		}, nil},
+		{"required", func(in *input) {
+			in.Domain = ""
+			in.Untagged = ""
+		}, []Violation{{"/domain", "is required"}, {"/Untagged", "is required"}}},
+		{"enum", func(in *input) { in.KeyType = "DSA" }, []Violation{{"/key_type", "must be one of EC256, RSA2048"}}},
+		{"min number", func(in *input) { in.Days = intPtr(0) }, []Violation{{"/days", "must be at least 1"}}},
+		{"max number", func(in *input) { in.Days = intPtr(91) }, []Violation{{"/days", "must be at most 90"}}},
+		{"max float", func(in *input) { in.Ratio = 0.51 }, []Violation{{"/ratio", "must be at most 0.5"}}},
+		{"min string", func(in *input) { in.Name = "é" }, []Violation{{"/name", "must be at least 2 characters long"}}},
+		{"max string", func(in *input) { in.Name = "hello" }, []Violation{{"/name", "must be at most 4 characters long"}}},
+		{"max slice", func(in *input) { in.Tags = []string{"a", "b", "a"} }, []Violation{{"/tags", "must be at most 2 elements long"}}},
+		{"enum of slice elements", func(in *input) { in.Tags = []string{"a", "c"} }, []Violation{
+			{"/tags/1", "must be one of a, b"},
+			{"/tags/1", "must match pattern [ab]"},
+		}},
+		{"pattern", func(in *input) { in.Domain = "Example.com" }, []Violation{{"/domain", "must match pattern [a-z0-9.-]+"}}},
+		{"pattern matches the whole value", func(in *input) { in.Domain = "example.com/" }, []Violation{{"/domain", "must match pattern [a-z0-9.-]+"}}},
+		{"empty map", func(in *input) { in.Labels = map[string]string{} }, nil},
+		{"nested struct", func(in *input) { in.Address = &address{} }, []Violation{{"/address/city", "is required"}}},
+		{"slice of structs", func(in *input) { in.Others = []address{{City: "Haifa"}, {}} }, []Violation{{"/others/1/city", "is required"}}},
+		{"map of structs", func(in *input) { in.ByName = map[string]address{"a/b~c": {}} }, []Violation{{"/by_name/a~1b~0c/city", "is required"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := valid()
+			tt.modify(&in)
+
+			if got := Struct(&in); !slices.Equal(got, tt.want) {
+				t.Errorf("Struct() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructEscapesFieldNames(t *testing.T) {
+	v := struct {
+		Path string `json:"a/b~c" validate:"required"`
+	}{}
+
+	want := []Violation{{"/a~1b~0c", "is required"}}
+	if got := Struct(v); !slices.Equal(got, want) {
+		t.Errorf("Struct() = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want Violation
+	}{
+		{"wrong type", `{"days":"ten"}`, Violation{"/days", "must be int"}},
+		{"wrong nested type", `{"address":{"city":1}}`, Violation{"/address/city", "must be string"}},
+		{"unknown field", `{"domains":[]}`, Violation{"/domains", "unknown field"}},
+		{"not an object", `"example.com"`, Violation{"", "must be a JSON object"}},
+		{"malformed", `{"domain":`, Violation{"", "must be a JSON object"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := json.NewDecoder(strings.NewReader(tt.json))
+			dec.DisallowUnknownFields()
+
+			var in input
+
+			err := dec.Decode(&in)
+			if err == nil {
+				t.Fatal("Decode() succeeded")
+			}
+
+			// the decoded document, which may include secrets, isn't quoted
+			if got := DecodeError(err); got != tt.want {
+				t.Errorf("DecodeError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	type recursive struct {
+		Name     string       `json:"name" validate:"required"`
+		Children []*recursive `json:"children"`
+	}
+
+	if err := Check(reflect.TypeOf(&input{})); err != nil {
+		t.Errorf("Check() of valid rules failed: %v", err)
+	}
+
+	if err := Check(reflect.TypeOf(recursive{})); err != nil {
+		t.Errorf("Check() of a recursive type failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		v       interface{}
+		wantErr string
+	}{
+		{"unknown rule", struct {
+			A string `validate:"requird"`
+		}{}, "unknown rule 'requird'"},
+		{"invalid number", struct {
+			A int `validate:"min=one"`
+		}{}, "invalid min rule argument 'one'"},
+		{"min of bool", struct {
+			A bool `validate:"min=1"`
+		}{}, "min rule doesn't apply to bool"},
+		{"enum of int", struct {
+			A []int `validate:"enum=1|2"`
+		}{}, "enum rule doesn't apply to []int"},
+		{"invalid pattern", struct {
+			A string `pattern:"[a-"`
+		}{}, "invalid pattern '[a-'"},
+		{"pattern of int", struct {
+			A *int `pattern:"[0-9]+"`
+		}{}, "pattern doesn't apply to *int"},
+		{"nested", struct {
+			A map[string][]struct {
+				B string `validate:"max"`
+			}
+		}{}, "invalid max rule argument ''"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(reflect.TypeOf(tt.v))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
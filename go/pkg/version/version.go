@@ -0,0 +1,51 @@
+// Package version reports build information of producer binaries.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is the release version of the binary. It is set at build time:
+//
+//	go build -ldflags "-X github.com/akeylesslabs/custom-producer/go/pkg/version.Version=v1.2.3"
+var Version = "dev"
+
+// Info is build information of the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+	Path      string `json:"path,omitempty"`
+}
+
+// Get returns build information of the running binary. VCS details are only
+// available if the binary was built from a git checkout.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	info.Path = bi.Path
+
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.BuildTime = s.Value
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/audit"
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+// SerialNumberer is implemented by producer responses that include a
+// certificate. Its serial number is stored in audit records.
+type SerialNumberer interface {
+	SerialNumber() string
+}
+
+func serialNumber(response interface{}) string {
+	if s, ok := response.(SerialNumberer); ok {
+		return s.SerialNumber()
+	}
+
+	return ""
+}
+
+// record completes the record with the request details and the operation
+// outcome, and writes it. Failing to write an audit record doesn't fail the
+// request, since the credentials were already issued or revoked by then.
+func (h *hook) record(r *http.Request, rec *audit.Record, err error) {
+	if h.auditLogger == nil {
+		return
+	}
+
+	rec.ItemName = h.itemName
+	rec.CorrelationID, _ = r.Context().Value(correlationKey{}).(string)
+	rec.Outcome = audit.OutcomeSuccess
+
+	if err != nil {
+		var pErr *protocol.Error
+		if !errors.As(err, &pErr) {
+			pErr = protocol.ErrInternal
+		}
+
+		rec.Outcome = audit.OutcomeFailure
+		rec.ErrorCode = pErr.Code
+	}
+
+	if err := h.auditLogger.Log(r.Context(), rec); err != nil {
+		logging.FromContext(r.Context()).Error("failed to write audit record", "error", err)
+	}
+}
@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+// defaultMaxBodySize is large enough for any producer request, while
+// preventing callers from exhausting memory.
+const defaultMaxBodySize = 1 << 20
+
+var (
+	errBodyTooLarge     = protocol.NewError("request_too_large", http.StatusRequestEntityTooLarge, "request body is too large")
+	errUnsupportedMedia = protocol.NewError("unsupported_media_type", http.StatusUnsupportedMediaType, "request body must be application/json")
+	errEmptyBody        = errInvalidBody.WithMessage("request body must be a JSON object")
+)
+
+// decode reads the request body into a new T, and returns it together with
+// the raw body. Bodies that are too large, aren't JSON, or decode to null are
+// rejected.
+func decode[T any](h *hook, r *http.Request) (*T, []byte, error) {
+	// requests without Content-Type are accepted for compatibility with
+	// older gateways
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || mt != "application/json" {
+			return nil, nil, errUnsupportedMedia
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, h.maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, nil, errBodyTooLarge.Wrap(err)
+		}
+
+		return nil, nil, errInvalidBody.Wrap(err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if h.strict {
+		dec.DisallowUnknownFields()
+	}
+
+	var v *T
+	if err := dec.Decode(&v); err != nil {
+		return nil, nil, errInvalidBody.WithMessage("invalid request body: %s", jsonError(err)).Wrap(err)
+	}
+
+	if dec.More() {
+		return nil, nil, errInvalidBody.WithMessage("invalid request body: unexpected data after JSON object")
+	}
+
+	if v == nil {
+		return nil, nil, errEmptyBody
+	}
+
+	return v, body, nil
+}
+
+// jsonError describes a decoding error without quoting the request body, as
+// it may include secrets.
+func jsonError(err error) string {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.As(err, &syntaxErr):
+		return "malformed JSON"
+	case errors.As(err, &typeErr):
+		return "invalid type of field " + typeErr.Field
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "unexpected end of JSON"
+	default:
+		// unknown fields are reported as `json: unknown field "name"`,
+		// which only includes the field name
+		return err.Error()
+	}
+}
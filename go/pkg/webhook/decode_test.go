@@ -0,0 +1,122 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+// fuzzMaxBodySize keeps the size limit reachable by the fuzzer.
+const fuzzMaxBodySize = 4 << 10
+
+var decodeSeeds = []string{
+	``,
+	`null`,
+	`{}`,
+	`[]`,
+	`"payload"`,
+	`{"payload":"p"}`,
+	`{"payload":"p"}{"payload":"p"}`,
+	`{"payload":"p"} trailing`,
+	`{"payload":1}`,
+	`{"payload":"p","unknown":true}`,
+	`{"payload":"p","client_info":{"access_id":"p-custom","sub_claims":{"email":["a@example.com"]}},"input":{"domain":"example.com"}}`,
+	`{"payload":"p","input":""}`,
+	`{"payload":"p","input":null}`,
+	`{"payload":"p","input":"{\"domain\":\"example.com\"}"}`,
+	`{"payload":"p","ids":["id-1","id-2"]}`,
+	`{"payload":"p","ids":"id-1"}`,
+	`{"payload":"enc:v1:AAAA"}`,
+	`{"payload":"\ud800"}`,
+	`{"payload":"p"`,
+	`{"a":` + string(bytes.Repeat([]byte(`[`), 10000)),
+	string(bytes.Repeat([]byte(" "), fuzzMaxBodySize+1)),
+}
+
+// fuzzDecode checks that decoding any body into T never panics, fails only
+// with client errors, and that strict decoding accepts a subset of what
+// lenient decoding does.
+func fuzzDecode[T any](f *testing.F) {
+	for _, seed := range decodeSeeds {
+		f.Add([]byte(seed), "application/json")
+	}
+
+	f.Add([]byte(`{"payload":"p"}`), "")
+	f.Add([]byte(`{"payload":"p"}`), "application/json; charset=utf-8")
+	f.Add([]byte(`{"payload":"p"}`), "text/plain")
+	f.Add([]byte(`{"payload":"p"}`), "application/json; charset")
+
+	lenient := &hook{maxBodySize: fuzzMaxBodySize}
+	strict := &hook{maxBodySize: fuzzMaxBodySize, strict: true}
+
+	f.Fuzz(func(t *testing.T, body []byte, contentType string) {
+		decodeBody := func(h *hook) (*T, []byte, error) {
+			r := httptest.NewRequest(http.MethodPost, "/sync/create", bytes.NewReader(body))
+			if contentType != "" {
+				r.Header.Set("Content-Type", contentType)
+			}
+
+			return decode[T](h, r)
+		}
+
+		v, raw, err := decodeBody(lenient)
+		checkDecoded(t, body, v, raw, err)
+
+		sv, sraw, serr := decodeBody(strict)
+		checkDecoded(t, body, sv, sraw, serr)
+
+		if serr == nil && err != nil {
+			t.Errorf("strict decoding accepted a body lenient decoding rejected: %v", err)
+		}
+	})
+}
+
+func checkDecoded[T any](t *testing.T, body []byte, v *T, raw []byte, err error) {
+	t.Helper()
+
+	if err != nil {
+		var pErr *protocol.Error
+		if !errors.As(err, &pErr) {
+			t.Fatalf("decode returned %T, want *protocol.Error: %v", err, err)
+		}
+
+		if pErr.Status < 400 || pErr.Status >= 500 {
+			t.Fatalf("decode returned status %d, want a client error: %v", pErr.Status, err)
+		}
+
+		return
+	}
+
+	if v == nil {
+		t.Fatal("decode succeeded without a value")
+	}
+
+	if !bytes.Equal(raw, body) {
+		t.Fatal("decode returned a body that differs from the request")
+	}
+
+	if len(body) > fuzzMaxBodySize {
+		t.Fatalf("decode accepted a body of %d bytes", len(body))
+	}
+
+	if !json.Valid(body) {
+		t.Fatal("decode accepted invalid JSON")
+	}
+}
+
+func FuzzDecodeCreate(f *testing.F) {
+	fuzzDecode[protocol.CreateRequest](f)
+}
+
+func FuzzDecodeRevoke(f *testing.F) {
+	fuzzDecode[protocol.RevokeRequest](f)
+}
+
+func FuzzDecodeRotate(f *testing.F) {
+	fuzzDecode[protocol.RotateRequest](f)
+}
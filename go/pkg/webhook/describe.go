@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/schema"
+	"github.com/akeylesslabs/custom-producer/go/pkg/version"
+)
+
+// Output formats of the response field of create responses.
+const (
+	formatJSON = "json"
+	formatJWE  = "jwe"
+)
+
+// description is the response of /sync/describe endpoint.
+type description struct {
+	Name       string   `json:"name,omitempty"`
+	Version    string   `json:"version"`
+	Operations []string `json:"operations"`
+	// Input and Payload are JSON Schemas, omitted if the producer doesn't
+	// describe them.
+	Input   *schema.Schema `json:"input,omitempty"`
+	Payload *schema.Schema `json:"payload,omitempty"`
+	// PayloadEncrypted reports whether the payload must be encrypted with
+	// one of the payload keys of the webhook.
+	PayloadEncrypted bool     `json:"payload_encrypted,omitempty"`
+	OutputFormats    []string `json:"output_formats"`
+}
+
+// describe reports the capabilities of the producer, as configured in this
+// webhook. The description doesn't change, so it is built once.
+func (h *hook) describe(p protocol.Producer) wrapperFunc {
+	d := &description{
+		Name:             h.name,
+		Version:          version.Get().Version,
+		PayloadEncrypted: len(h.payloadKeys) > 0,
+	}
+
+	var pd protocol.Description
+
+	if dp, ok := p.(protocol.Describer); ok {
+		pd = dp.Describe()
+		d.Input = schema.For(pd.Input)
+		d.Payload = schema.For(pd.Payload)
+	}
+
+	d.Operations = operations(p, pd.Operations)
+
+	switch h.encryption {
+	case encryptionDisabled:
+		d.OutputFormats = []string{formatJSON}
+	case encryptionOptional:
+		d.OutputFormats = []string{formatJSON, formatJWE}
+	case encryptionRequired:
+		d.OutputFormats = []string{formatJWE}
+	}
+
+	if h.encryption != encryptionDisabled {
+		if d.Input == nil {
+			d.Input = &schema.Schema{Schema: schema.Version, Type: "object"}
+		}
+
+		// the key may be in the input or in the payload, so neither
+		// schema can require it
+		desc := "PEM or JWK public key to encrypt the response to, in the input or in the payload"
+		if h.encryption == encryptionRequired {
+			desc += ". Required in one of them"
+		}
+
+		for _, sc := range []*schema.Schema{d.Input, d.Payload} {
+			if sc == nil {
+				continue
+			}
+
+			if sc.Properties == nil {
+				sc.Properties = make(map[string]*schema.Schema)
+			}
+
+			sc.Properties[encryptionKeyField] = &schema.Schema{Type: "string", Description: desc}
+		}
+	}
+
+	return func(*http.Request) (interface{}, error) {
+		return d, nil
+	}
+}
+
+// operations returns the operations the producer supports: the ones it
+// declares, if any, and that the webhook serves for it.
+func operations(p protocol.Producer, declared []string) []string {
+	ops := []string{opCreate, opRevoke}
+
+	if _, ok := p.(protocol.Rotator); ok {
+		ops = append(ops, opRotate)
+	}
+
+	if declared == nil {
+		return ops
+	}
+
+	return slices.DeleteFunc(ops, func(op string) bool {
+		return !slices.Contains(declared, op)
+	})
+}
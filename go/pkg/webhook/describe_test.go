@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/schema"
+)
+
+type testInput struct {
+	Domain string `json:"domain" validate:"required"`
+}
+
+type testPayload struct {
+	Region string `json:"region"`
+}
+
+// describingProducer is a testProducer that describes its input and payload.
+type describingProducer struct {
+	testProducer
+}
+
+func (*describingProducer) Describe() protocol.Description {
+	return protocol.Description{Input: testInput{}, Payload: testPayload{}}
+}
+
+func getDescription(t *testing.T, h http.Handler, path string) *description {
+	t.Helper()
+
+	// operators read the description without credentials
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("describe returned %d: %s", rec.Code, rec.Body)
+	}
+
+	var d description
+	if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil {
+		t.Fatalf("describe returned invalid JSON: %v", err)
+	}
+
+	return &d
+}
+
+func TestDescribeWithoutCredentials(t *testing.T) {
+	h := newTestHandler(t, &describingProducer{})
+
+	d := getDescription(t, h, "/sync/describe")
+
+	if d.Input == nil || d.Input.Properties["domain"] == nil {
+		t.Errorf("describe didn't return the input schema: %+v", d.Input)
+	}
+
+	// other endpoints still require credentials
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sync/create", nil))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("create without credentials returned %d, want 401", rec.Code)
+	}
+}
+
+func TestDescribeWithoutCredentialsMux(t *testing.T) {
+	stubAuth(t)
+
+	h, err := NewMux(Route{
+		Name:     "test",
+		Prefix:   "/test",
+		Producer: &describingProducer{},
+		Options:  []Option{WithAllowedAccessID(testAccessID)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := getDescription(t, h, "/test/sync/describe"); d.Name != "test" {
+		t.Errorf("describe returned producer %q, want test", d.Name)
+	}
+}
+
+func TestDescribeEncryptionKeyNotRequired(t *testing.T) {
+	h := newTestHandler(t, &describingProducer{}, WithResponseEncryption(true))
+
+	d := getDescription(t, h, "/sync/describe")
+
+	for name, sc := range map[string]*schema.Schema{"input": d.Input, "payload": d.Payload} {
+		if sc == nil || sc.Properties[encryptionKeyField] == nil {
+			t.Errorf("%s schema doesn't describe %s", name, encryptionKeyField)
+			continue
+		}
+
+		// the key may be in either of them
+		for _, field := range sc.Required {
+			if field == encryptionKeyField {
+				t.Errorf("%s schema requires %s", name, encryptionKeyField)
+			}
+		}
+	}
+
+	if len(d.OutputFormats) != 1 || d.OutputFormats[0] != formatJWE {
+		t.Errorf("output formats are %v, want [jwe]", d.OutputFormats)
+	}
+}
+
+// rotatingProducer only supports rotation, although it implements
+// protocol.Producer.
+type rotatingProducer struct {
+	testProducer
+}
+
+func (*rotatingProducer) Describe() protocol.Description {
+	return protocol.Description{Operations: []string{protocol.OperationRotate}}
+}
+
+func TestDescribeOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		p    protocol.Producer
+		want []string
+	}{
+		{"every operation", &testProducer{}, []string{"create", "revoke", "rotate"}},
+		{"declared operations", &rotatingProducer{}, []string{"rotate"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := getDescription(t, newTestHandler(t, tt.p), "/sync/describe")
+
+			if !slices.Equal(d.Operations, tt.want) {
+				t.Errorf("operations are %v, want %v", d.Operations, tt.want)
+			}
+		})
+	}
+}
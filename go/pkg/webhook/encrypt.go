@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/e2e"
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+type encryptionMode int
+
+const (
+	encryptionDisabled encryptionMode = iota
+	encryptionOptional
+	encryptionRequired
+)
+
+// encryptionKeyField is the field of the input, or of a JSON payload, that
+// holds the public key to encrypt responses to.
+const encryptionKeyField = payload.EncryptionKeyField
+
+var (
+	errEncryptionKey      = protocol.NewError(protocol.CodeInvalidInput, http.StatusBadRequest, "invalid encryption key")
+	errEncryptionRequired = protocol.NewError(protocol.CodeInvalidInput, http.StatusBadRequest, encryptionKeyField+" is required")
+)
+
+// encryptionKey returns the public key provided in the input, or in the
+// payload if the input doesn't include one.
+func encryptionKey(cr *protocol.CreateRequest) (interface{}, error) {
+	var fields struct {
+		Key string `json:"encryption_key"`
+	}
+
+	if err := cr.Input.Decode(&fields); err != nil || fields.Key == "" {
+		// payloads aren't always JSON, so errors are expected
+		_ = json.Unmarshal([]byte(cr.Payload), &fields)
+	}
+
+	if fields.Key == "" {
+		return nil, nil
+	}
+
+	key, err := e2e.ParsePublicKey(fields.Key)
+	if err != nil {
+		return nil, errEncryptionKey.WithMessage("invalid %s: %s", encryptionKeyField, err).Wrap(err)
+	}
+
+	return key, nil
+}
+
+// encrypt replaces the response field with a JWE encrypted to key.
+func encrypt(out *protocol.CreateResponse, key interface{}) (*protocol.CreateResponse, error) {
+	token, err := e2e.Encrypt(out.Response, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &protocol.CreateResponse{ID: out.ID, Response: token}, nil
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/e2e"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+func TestCreateEncrypted(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input, err := json.Marshal(map[string]string{
+		encryptionKeyField: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newTestHandler(t, &testProducer{}, WithResponseEncryption(true))
+
+	rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p","input":`+string(input)+`}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create returned %d: %s", rec.Code, rec.Body)
+	}
+
+	var res struct {
+		ID       string      `json:"id"`
+		Response interface{} `json:"response"`
+	}
+
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the ID, which Akeyless needs to revoke credentials, is readable
+	if _, ok := res.Response.(string); !ok || strings.Contains(rec.Body.String(), `"user"`) {
+		t.Fatalf("create returned %s, want an encrypted response", rec.Body)
+	}
+
+	var got map[string]string
+	if err := e2e.DecryptCreateResponse(rec.Body.Bytes(), key, &got); err != nil {
+		t.Fatalf("can't decrypt response: %v", err)
+	}
+
+	if got["user"] != res.ID {
+		t.Errorf("decrypted response is %v, want the user %s", got, res.ID)
+	}
+}
+
+func TestCreateEncryptionRequired(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"without key", `{"payload":"p"}`, http.StatusBadRequest},
+		{"invalid key", `{"payload":"p","input":{"encryption_key":"s3cr3t"}}`, http.StatusBadRequest},
+		{"dry run", `{"payload":"p","client_info":{"access_id":"` + protocol.DryRunAccessID + `"}}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &testProducer{}
+			h := newTestHandler(t, p, WithResponseEncryption(true))
+
+			rec := serve(h, http.MethodPost, "/sync/create", tt.body, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("create returned %d %s, want %d", rec.Code, rec.Body, tt.wantCode)
+			}
+
+			if tt.wantCode != http.StatusOK && p.createCount() != 0 {
+				t.Error("producer issued credentials that can't be encrypted")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+const correlationIDHeader = "X-Correlation-ID"
+
+var (
+	errInvalidBody   = protocol.NewError(protocol.CodeBadRequest, http.StatusBadRequest, "can't read request body")
+	errUnauthorized  = protocol.NewError(protocol.CodeUnauthorized, http.StatusUnauthorized, "invalid credentials")
+	validCorrelation = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+)
+
+type correlationKey struct{}
+
+// newCorrelationID returns the correlation ID provided by the caller, or
+// generates a new one. It is sent back in every response and included in
+// logs, so that a failed request can be traced back to its log entries.
+func newCorrelationID(r *http.Request) string {
+	if id := r.Header.Get(correlationIDHeader); validCorrelation.MatchString(id) {
+		return id
+	}
+
+	bs := make([]byte, 8)
+	if _, err := rand.Read(bs); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(bs)
+}
+
+// withCorrelationID stores the correlation ID in request context, together
+// with a logger that includes it in every entry.
+func withCorrelationID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), correlationKey{}, id)
+	ctx = logging.WithContext(ctx, logging.FromContext(ctx).With("correlation_id", id))
+
+	return r.WithContext(ctx)
+}
+
+// writeError writes the provided error as a protocol.ErrorResponse. Errors
+// that aren't *protocol.Error are reported as internal errors without any
+// details.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	id, _ := r.Context().Value(correlationKey{}).(string)
+
+	logger := logging.FromContext(r.Context())
+	logger.Error("request ended with error", "path", r.URL.Path, "error", err)
+
+	var pErr *protocol.Error
+	if !errors.As(err, &pErr) {
+		pErr = protocol.ErrInternal
+	}
+
+	if pErr.RetryAfter > 0 {
+		// Retry-After is in whole seconds, round up so that the caller
+		// never retries too early
+		secs := (pErr.RetryAfter + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.Itoa(int(secs)))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(pErr.Status)
+
+	if err := json.NewEncoder(w).Encode(protocol.NewErrorResponse(pErr, id)); err != nil {
+		logger.Error("failed to write error response", "error", err)
+	}
+}
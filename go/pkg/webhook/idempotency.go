@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+var errIdempotencyKeyReused = protocol.NewError("idempotency_key_reused", http.StatusUnprocessableEntity, "idempotency key was already used with another request")
+
+// idempotentResult is kept for each idempotency key. Request is a
+// fingerprint of the payload and input the key was first used with.
+type idempotentResult struct {
+	Request  string          `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// idempotentRequest is the part of a create or rotate request that
+// identifies it.
+type idempotentRequest struct {
+	op         string
+	payload    string
+	clientInfo protocol.ClientInfo
+	input      protocol.Input
+}
+
+// idempotencyKey identifies repeated create and rotate requests. If the
+// caller provides Idempotency-Key header, it is used together with the
+// client's access ID. Otherwise, the key is a fingerprint of the payload,
+// input and client info.
+func (h *hook) idempotencyKey(r *http.Request, req idempotentRequest) string {
+	fp := struct {
+		Operation  string              `json:"operation"`
+		ItemName   string              `json:"item_name"`
+		Key        string              `json:"key,omitempty"`
+		Payload    string              `json:"payload,omitempty"`
+		ClientInfo protocol.ClientInfo `json:"client_info"`
+		Input      protocol.Input      `json:"input,omitempty"`
+	}{
+		Operation:  req.op,
+		ItemName:   h.itemName,
+		ClientInfo: req.clientInfo,
+	}
+
+	if key := r.Header.Get(idempotencyKeyHeader); validCorrelation.MatchString(key) {
+		fp.Key = key
+	} else {
+		fp.Payload = req.payload
+		fp.Input = req.input
+	}
+
+	// SubClaims is a map, which is marshaled with sorted keys, so that the
+	// same request always results in the same fingerprint
+	bs, err := json.Marshal(fp)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(bs)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// requestFingerprint identifies the payload and input of a request.
+func requestFingerprint(req idempotentRequest) string {
+	h := sha256.New()
+	_ = json.NewEncoder(h).Encode([]interface{}{req.payload, req.input})
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotent runs create or rotate at most once per idempotency key.
+// Repeated requests get the response of the first one, without calling the
+// producer again. Requests with replayed credentials never call the producer,
+// and are rejected unless the first request succeeded or is still in flight.
+// An Idempotency-Key reused with another payload or input is rejected, rather
+// than returning credentials issued for the first request.
+func (h *hook) idempotent(r *http.Request, req idempotentRequest, run func() (interface{}, error)) (interface{}, error) {
+	if replayed(r.Context()) {
+		run = func() (interface{}, error) {
+			authResults.WithLabelValues(h.name, resultReplay).Inc()
+			return nil, errReplayed
+		}
+	}
+
+	if h.idempotency == nil {
+		return run()
+	}
+
+	key := h.idempotencyKey(r, req)
+	if key == "" {
+		return run()
+	}
+
+	fp := requestFingerprint(req)
+
+	out, replayed, err := h.idempotency.Do(r.Context(), key, func() ([]byte, error) {
+		out, err := run()
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(&idempotentResult{Request: fp, Response: res})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res idempotentResult
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, fmt.Errorf("can't unmarshal idempotent result: %w", err)
+	}
+
+	if replayed && res.Request != fp {
+		return nil, errIdempotencyKeyReused
+	}
+
+	if replayed {
+		logging.FromContext(r.Context()).Info("replayed response of an earlier request", "operation", req.op, "idempotency_key", key)
+	}
+
+	return res.Response, nil
+}
@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/idempotency"
+)
+
+func TestIdempotentCreate(t *testing.T) {
+	p := &testProducer{}
+	h := newTestHandler(t, p, WithIdempotency(idempotency.NewMemory(), time.Minute))
+
+	key := http.Header{idempotencyKeyHeader: {"retry-1"}}
+
+	first := serve(h, http.MethodPost, "/sync/create", `{"payload":"p","input":{"domain":"a.example.com"}}`, key)
+	if first.Code != http.StatusOK {
+		t.Fatalf("create returned %d: %s", first.Code, first.Body)
+	}
+
+	retry := serve(h, http.MethodPost, "/sync/create", `{"payload":"p","input":{"domain":"a.example.com"}}`, key)
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry returned %d %s, want the first response %s", retry.Code, retry.Body, first.Body)
+	}
+
+	// credentials of the first request must not be returned for another one
+	reused := serve(h, http.MethodPost, "/sync/create", `{"payload":"p","input":{"domain":"b.example.com"}}`, key)
+	if reused.Code != http.StatusUnprocessableEntity {
+		t.Errorf("create reusing the key returned %d %s, want 422", reused.Code, reused.Body)
+	}
+
+	if n := p.createCount(); n != 1 {
+		t.Errorf("producer was called %d times, want once", n)
+	}
+
+	// without a key, requests are told apart by their content
+	other := serve(h, http.MethodPost, "/sync/create", `{"payload":"p","input":{"domain":"b.example.com"}}`, nil)
+	if other.Code != http.StatusOK || other.Body.String() == first.Body.String() {
+		t.Errorf("another create returned %d %s, want a new response", other.Code, other.Body)
+	}
+}
+
+func TestIdempotentCreateFailureNotCached(t *testing.T) {
+	p := &testProducer{createErr: errRateLimited}
+	h := newTestHandler(t, p, WithIdempotency(idempotency.NewMemory(), time.Minute))
+
+	if rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p"}`, nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("create returned %d, want 429", rec.Code)
+	}
+
+	p.mu.Lock()
+	p.createErr = nil
+	p.mu.Unlock()
+
+	if rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p"}`, nil); rec.Code != http.StatusOK {
+		t.Errorf("retry returned %d, want 200", rec.Code)
+	}
+
+	if n := p.createCount(); n != 2 {
+		t.Errorf("producer was called %d times, want 2", n)
+	}
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/validate"
+)
+
+var errInvalidInput = protocol.NewError(protocol.CodeInvalidInput, http.StatusBadRequest, "invalid input")
+
+// inputType returns the type of the input described by the producer, or nil
+// if the producer doesn't describe it.
+func inputType(p protocol.Producer) reflect.Type {
+	d, ok := p.(protocol.Describer)
+	if !ok {
+		return nil
+	}
+
+	in := d.Describe().Input
+	if in == nil {
+		return nil
+	}
+
+	t := reflect.TypeOf(in)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// checkRules reports invalid validation rules of the input and payload
+// described by the producer, so that webhooks fail to start instead of
+// failing requests.
+func checkRules(p protocol.Producer) error {
+	d, ok := p.(protocol.Describer)
+	if !ok {
+		return nil
+	}
+
+	desc := d.Describe()
+
+	for _, v := range []interface{}{desc.Input, desc.Payload} {
+		if v == nil {
+			continue
+		}
+
+		if err := validate.Check(reflect.TypeOf(v)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateInput decodes the input into a new value of type t, and checks it
+// against the rules declared in its struct tags, so that producers are only
+// called with valid input. Every violation is reported in error details.
+//
+// The rules of dry runs, which are usually made without input, aren't
+// checked, but their input must still decode into t.
+func validateInput(t reflect.Type, in protocol.Input, dryRun bool) error {
+	if t == nil {
+		return nil
+	}
+
+	v := reflect.New(t).Interface()
+
+	if len(in) > 0 {
+		if err := json.Unmarshal(in, v); err != nil {
+			return errInvalidInput.WithDetails([]validate.Violation{validate.DecodeError(err)}).Wrap(err)
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	if violations := validate.Struct(v); len(violations) > 0 {
+		return errInvalidInput.WithDetails(violations)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+func TestDryRunSkipsInputValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"plain", nil},
+		{"response encryption required", []Option{WithResponseEncryption(true)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &describingProducer{}
+			h := newTestHandler(t, p, tt.opts...)
+
+			// the domain required by the input is missing
+			for _, body := range []string{
+				`{"payload":"p","client_info":{"access_id":"p-custom"}}`,
+				`{"payload":"p","client_info":{"access_id":"p-custom"},"input":{}}`,
+			} {
+				if rec := serve(h, http.MethodPost, "/sync/create", body, nil); rec.Code != http.StatusOK {
+					t.Errorf("dry run %s returned %d: %s", body, rec.Code, rec.Body)
+				}
+			}
+
+			if n := p.createCount(); n != 2 {
+				t.Errorf("producer got %d dry runs, want 2", n)
+			}
+
+			rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p","client_info":{"access_id":"p-user"},"input":{}}`, nil)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("create with invalid input returned %d, want 400", rec.Code)
+			}
+
+			if n := p.createCount(); n != 2 {
+				t.Error("producer was called with invalid input")
+			}
+		})
+	}
+}
+
+func TestDryRunRejectsInvalidJSON(t *testing.T) {
+	p := &describingProducer{}
+	h := newTestHandler(t, p)
+
+	for _, body := range []string{
+		`{"payload":"p","client_info":{"access_id":"p-custom"},"input":{"domain":5}}`,
+		`{"payload":"p","client_info":{"access_id":"p-custom"},"input":"example.com"}`,
+		`{"payload":"p","client_info":{"access_id":"p-custom"},"input":{`,
+	} {
+		if rec := serve(h, http.MethodPost, "/sync/create", body, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("dry run %s returned %d, want 400", body, rec.Code)
+		}
+	}
+
+	if n := p.createCount(); n != 0 {
+		t.Errorf("producer got %d dry runs with invalid JSON", n)
+	}
+}
+
+// invalidRulesProducer describes an input with a rule that doesn't apply to
+// its field.
+type invalidRulesProducer struct {
+	testProducer
+}
+
+func (*invalidRulesProducer) Describe() protocol.Description {
+	return protocol.Description{Input: struct {
+		Enabled bool `json:"enabled" validate:"enum=yes|no"`
+	}{}}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	if _, err := New(&invalidRulesProducer{}, WithAllowedAccessID(testAccessID)); err == nil || !strings.Contains(err.Error(), "Enabled") {
+		t.Errorf("New() = %v, want an error about the field", err)
+	}
+
+	_, err := NewMux(Route{Name: "test", Prefix: "/test", Producer: &invalidRulesProducer{}})
+	if err == nil || !strings.Contains(err.Error(), "producer test") {
+		t.Errorf("NewMux() = %v, want an error about the producer", err)
+	}
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"errors"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Operations, as reported in metric labels.
+const (
+	opCreate   = protocol.OperationCreate
+	opRevoke   = protocol.OperationRevoke
+	opRotate   = protocol.OperationRotate
+	opDescribe = "describe"
+)
+
+// Results of authentication, as reported in metric labels.
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "akeyless_producer",
+		Name:      "requests_total",
+		Help:      "Number of producer operations by outcome. Outcome is 'success' or the error code.",
+	}, []string{"producer", "operation", "outcome"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "akeyless_producer",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of producer operations by outcome.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"producer", "operation", "outcome"})
+
+	authResults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "akeyless_producer",
+		Name:      "auth_total",
+		Help:      "Number of authentication attempts by result. Result is 'success', 'failure' or 'replay'.",
+	}, []string{"producer", "result"})
+
+	rateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "akeyless_producer",
+		Name:      "rate_limited_total",
+		Help:      "Number of create operations rejected by rate limits, by rule.",
+	}, []string{"producer", "rule"})
+)
+
+// observeRequest starts measuring a single operation of the named producer.
+// The returned function must be called with the operation result once it
+// completes.
+func observeRequest(producer, op string) func(error) {
+	start := time.Now()
+
+	return func(err error) {
+		outcome := resultSuccess
+
+		if err != nil {
+			var pErr *protocol.Error
+			if !errors.As(err, &pErr) {
+				pErr = protocol.ErrInternal
+			}
+
+			outcome = pErr.Code
+		}
+
+		requests.WithLabelValues(producer, op, outcome).Inc()
+		requestDuration.WithLabelValues(producer, op, outcome).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,169 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"github.com/gorilla/mux"
+)
+
+// Route registers a producer with a multiplexing handler created by NewMux.
+type Route struct {
+	// Name identifies the producer in metrics labels, logs and readiness
+	// checks.
+	Name string
+	// Prefix, if set, serves the producer under this path, for example,
+	// "/letsencrypt" serves "/letsencrypt/sync/create".
+	Prefix string
+	// Producer handles requests of this route.
+	Producer protocol.Producer
+	// Options configure the webhook of this route, including its auth
+	// policy and rate limits. If the route has an allowed item name, it is
+	// also selected for requests to "/sync/..." made by that item.
+	Options []Option
+}
+
+type authenticatedKey struct{}
+
+// itemKey identifies a producer selected by the item that made a request.
+type itemKey struct {
+	accessID string
+	itemName string
+}
+
+type itemRoute struct {
+	h       *hook
+	handler http.Handler
+}
+
+// NewMux creates a handler that serves several producers. Each producer is
+// served under its path prefix, and producers with an allowed item name are
+// also selected by the item that made the request to "/sync/...". Probes and
+// metrics are served once for all producers.
+//
+// Selecting by item name costs a call to Akeyless authentication service for
+// every access ID of such producers, so producers selected by item name
+// should share an access ID. Describe requests to "/sync/describe" need
+// credentials too, to select the producer, while "<prefix>/sync/describe"
+// doesn't.
+func NewMux(routes ...Route) (http.Handler, error) {
+	root := &hook{checks: map[string]readinessCheck{}}
+
+	router := mux.NewRouter()
+
+	router.Use(root.correlate, root.trace, root.recoverPanic)
+
+	var accessIDs []string
+
+	byItem := make(map[itemKey]itemRoute)
+	names := make(map[string]bool)
+	prefixes := make(map[string]bool)
+
+	for _, rt := range routes {
+		if rt.Name == "" || names[rt.Name] {
+			return nil, fmt.Errorf("producer names must be unique and not empty, got '%s'", rt.Name)
+		}
+
+		names[rt.Name] = true
+
+		if err := checkRules(rt.Producer); err != nil {
+			return nil, fmt.Errorf("producer %s: %w", rt.Name, err)
+		}
+
+		h := newHook(rt.Producer, rt.Options...)
+		h.name = rt.Name
+
+		for name, check := range h.checks {
+			// every producer checks the same auth service
+			if name != "auth" {
+				name = rt.Name + "/" + name
+			}
+
+			root.checks[name] = check
+		}
+
+		// each producer is registered once, and served both under its
+		// prefix and by its item name
+		hr := mux.NewRouter()
+		h.register(hr, "", rt.Producer)
+
+		if rt.Prefix != "" {
+			prefix := "/" + strings.Trim(rt.Prefix, "/")
+			if prefixes[prefix] || prefix == "/sync" {
+				return nil, fmt.Errorf("invalid or duplicated prefix '%s' of producer %s", rt.Prefix, rt.Name)
+			}
+
+			prefixes[prefix] = true
+
+			router.PathPrefix(prefix + "/sync/").Handler(http.StripPrefix(prefix, hr))
+		}
+
+		if h.itemName != "" {
+			key := itemKey{accessID: h.accessID, itemName: h.itemName}
+			if other, ok := byItem[key]; ok {
+				return nil, fmt.Errorf("producers %s and %s allow the same item '%s'", other.h.name, rt.Name, h.itemName)
+			}
+
+			if !slices.Contains(accessIDs, h.accessID) {
+				accessIDs = append(accessIDs, h.accessID)
+			}
+
+			byItem[key] = itemRoute{h: h, handler: hr}
+		} else if rt.Prefix == "" {
+			return nil, fmt.Errorf("producer %s must have a prefix or an allowed item name", rt.Name)
+		}
+	}
+
+	root.probes(router)
+
+	if len(byItem) > 0 {
+		router.PathPrefix("/sync/").Handler(root.selectByItem(accessIDs, byItem))
+	}
+
+	return router, nil
+}
+
+// selectByItem finds the item that made the request, and serves it with the
+// producer that allows this item. Credentials belong to a single access ID,
+// so the first access ID that accepts them identifies the item.
+func (h *hook) selectByItem(accessIDs []string, routes map[itemKey]itemRoute) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		creds := r.Header.Get(credsHeader)
+
+		var errs []error
+
+		for _, accessID := range accessIDs {
+			ctx, span := tracer.Start(r.Context(), "hook.auth")
+			id, err := identify(ctx, creds, accessID)
+			tracing.End(span, err)
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", accessID, err))
+				continue
+			}
+
+			ir, ok := routes[itemKey{accessID: id.AccessID, itemName: id.ItemName}]
+			if !ok {
+				errs = append(errs, fmt.Errorf("no producer allows item '%s' of %s", id.ItemName, accessID))
+				break
+			}
+
+			authResults.WithLabelValues(ir.h.name, resultSuccess).Inc()
+			logging.FromContext(ctx).Info("request authorized", "producer", ir.h.name, "access_id", id.AccessID, "item_name", id.ItemName)
+
+			ir.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authenticatedKey{}, ir.h)))
+
+			return
+		}
+
+		authResults.WithLabelValues("", resultFailure).Inc()
+		writeError(w, r, errUnauthorized.Wrap(errors.Join(errs...)))
+	})
+}
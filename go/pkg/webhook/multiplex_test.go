@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/auth"
+	"github.com/akeylesslabs/custom-producer/go/pkg/replay"
+)
+
+// stubIdentify identifies testCreds as the provided item of testAccessID,
+// and counts calls to Akeyless auth service.
+func stubIdentify(t *testing.T, itemName string) *atomic.Int32 {
+	t.Helper()
+
+	stubAuth(t)
+
+	var calls atomic.Int32
+
+	orig := identify
+	identify = func(_ context.Context, creds string, accessID string) (*auth.Identity, error) {
+		calls.Add(1)
+
+		if creds != testCreds || accessID != testAccessID {
+			return nil, errors.New("invalid credentials")
+		}
+
+		return &auth.Identity{AccessID: accessID, ItemName: itemName}, nil
+	}
+
+	t.Cleanup(func() { identify = orig })
+
+	return &calls
+}
+
+// newTestMux creates a multiplexing handler of a producer with the provided
+// prefix and allowed item name, and of another producer selected by the
+// "/other" item.
+func newTestMux(t *testing.T, p *testProducer, prefix string, itemName string, opts ...Option) http.Handler {
+	t.Helper()
+
+	h, err := NewMux(
+		Route{
+			Name:     "test",
+			Prefix:   prefix,
+			Producer: p,
+			Options:  append([]Option{WithAllowedAccessID(testAccessID), WithAllowedItemName(itemName)}, opts...),
+		},
+		Route{
+			Name:     "other",
+			Producer: &testProducer{},
+			Options:  []Option{WithAllowedAccessID(testAccessID), WithAllowedItemName("/other")},
+		},
+	)
+	if err != nil {
+		t.Fatalf("NewMux() failed: %v", err)
+	}
+
+	return h
+}
+
+func TestMuxPrefix(t *testing.T) {
+	calls := stubIdentify(t, "/test")
+
+	p := &testProducer{}
+	h := newTestMux(t, p, "/test", "")
+
+	if rec := serve(h, http.MethodPost, "/test/sync/create", `{"payload":"p"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("create returned %d: %s", rec.Code, rec.Body)
+	}
+
+	if p.createCount() != 1 {
+		t.Error("producer of the prefix wasn't called")
+	}
+
+	// the producer is authenticated by its own policy
+	if n := calls.Load(); n != 0 {
+		t.Errorf("item was identified %d times, want none", n)
+	}
+
+	if rec := serve(h, http.MethodPost, "/unknown/sync/create", `{"payload":"p"}`, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("create under an unknown prefix returned %d, want 404", rec.Code)
+	}
+}
+
+func TestMuxItem(t *testing.T) {
+	calls := stubIdentify(t, "/test")
+
+	p := &testProducer{}
+	h := newTestMux(t, p, "", "/test")
+
+	if rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("create returned %d: %s", rec.Code, rec.Body)
+	}
+
+	if p.createCount() != 1 {
+		t.Error("producer of the item wasn't called")
+	}
+
+	// producers sharing an access ID are selected with a single call
+	if n := calls.Load(); n != 1 {
+		t.Errorf("item was identified %d times, want once", n)
+	}
+}
+
+func TestMuxNoMatch(t *testing.T) {
+	stubIdentify(t, "/unknown")
+
+	p := &testProducer{}
+	h := newTestMux(t, p, "", "/test")
+
+	if rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p"}`, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("create of an unknown item returned %d, want 401", rec.Code)
+	}
+
+	if rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p"}`, http.Header{credsHeader: {"stolen"}}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("create with invalid credentials returned %d, want 401", rec.Code)
+	}
+
+	if p.createCount() != 0 {
+		t.Error("producer was called for another item")
+	}
+}
+
+func TestMuxRegistersOnce(t *testing.T) {
+	stubIdentify(t, "/test")
+
+	p := &testProducer{}
+	h := newTestMux(t, p, "/test", "/test", WithReplayGuard(replay.NewMemory(), time.Minute))
+
+	// both routes share the same webhook, and its replay guard
+	if rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p"}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("create by item returned %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(h, http.MethodPost, "/test/sync/create", `{"payload":"p"}`, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("create replayed by prefix returned %d, want 401", rec.Code)
+	}
+}
+
+func TestMuxInvalidRoutes(t *testing.T) {
+	withItem := func(name string, itemName string) Route {
+		return Route{
+			Name:     name,
+			Producer: &testProducer{},
+			Options:  []Option{WithAllowedAccessID(testAccessID), WithAllowedItemName(itemName)},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		routes  []Route
+		wantErr string
+	}{
+		{"ambiguous item", []Route{withItem("a", "/item"), withItem("b", "/item")}, "allow the same item"},
+		{"duplicated name", []Route{withItem("a", "/a"), withItem("a", "/b")}, "must be unique"},
+		{"duplicated prefix", []Route{{Name: "a", Prefix: "/p", Producer: &testProducer{}}, {Name: "b", Prefix: "p/", Producer: &testProducer{}}}, "duplicated prefix"},
+		{"reserved prefix", []Route{{Name: "a", Prefix: "/sync", Producer: &testProducer{}}}, "invalid or duplicated prefix"},
+		{"unreachable", []Route{{Name: "a", Producer: &testProducer{}}}, "must have a prefix or an allowed item name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMux(tt.routes...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewMux() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/audit"
+	"github.com/akeylesslabs/custom-producer/go/pkg/idempotency"
+	"github.com/akeylesslabs/custom-producer/go/pkg/ratelimit"
+	"github.com/akeylesslabs/custom-producer/go/pkg/replay"
+	"github.com/akeylesslabs/custom-producer/go/pkg/sweeper"
+)
+
+// Option is a single configuration parameter used by this webhook.
+type Option func(*hook)
+
+// WithAllowedAccessID configures this webhook to accept requests only made by
+// a producer with the provided access ID.
+func WithAllowedAccessID(accessID string) Option {
+	return func(h *hook) {
+		h.accessID = accessID
+	}
+}
+
+// WithAllowedItemName configures this webhook to accept requests only made by
+// a producer with the provided name.
+func WithAllowedItemName(name string) Option {
+	return func(h *hook) {
+		h.itemName = name
+	}
+}
+
+// WithReadinessCheck adds a named check to /readyz endpoint. Checks of the
+// Akeyless auth service and of the producer itself are always included.
+func WithReadinessCheck(name string, check func(context.Context) error) Option {
+	return func(h *hook) {
+		h.checks[name] = check
+	}
+}
+
+// WithAuditLogger configures this webhook to record every create, revoke and
+// rotate operation in the provided audit log.
+func WithAuditLogger(l *audit.Logger) Option {
+	return func(h *hook) {
+		h.auditLogger = l
+	}
+}
+
+// WithRateLimit limits create operations using the provided limiter. Keys
+// returned by keys are prefixed with name, so that a single limiter may be
+// shared by several rules. Rejected requests get 429 status code with
+// Retry-After header.
+func WithRateLimit(name string, limiter ratelimit.Limiter, keys KeyFunc) Option {
+	return func(h *hook) {
+		h.rateLimits = append(h.rateLimits, rateLimit{name: name, limiter: limiter, keys: keys})
+	}
+}
+
+// WithConcurrencyLimit limits the number of create operations running at the
+// same time. Requests over the limit are rejected right away with 429 status
+// code.
+func WithConcurrencyLimit(c ratelimit.Concurrency) Option {
+	return func(h *hook) {
+		h.concurrency = c
+	}
+}
+
+// WithIdempotency configures this webhook to return the same response to
+// repeated create requests within the provided window, instead of issuing a
+// new credential. Requests are identified by Idempotency-Key header, or by a
+// fingerprint of the payload, input and client info. Successful responses,
+// including the issued credentials, are kept in store.
+func WithIdempotency(store idempotency.Store, window time.Duration) Option {
+	return func(h *hook) {
+		h.idempotency = idempotency.New(store, window)
+	}
+}
+
+// WithMaxBodySize limits the size of request bodies. Larger requests are
+// rejected with 413 status code. The default limit is 1 MiB.
+func WithMaxBodySize(n int64) Option {
+	return func(h *hook) {
+		h.maxBodySize = n
+	}
+}
+
+// WithStrictDecoding configures this webhook to reject requests that include
+// unknown fields.
+func WithStrictDecoding() Option {
+	return func(h *hook) {
+		h.strict = true
+	}
+}
+
+// WithReplayGuard configures this webhook to accept each combination of
+// credentials and request only once while the credentials are valid. Their
+// lifetime is read from credentials that are a JWT, otherwise the provided
+// window is used, which should be at least the lifetime of Akeyless
+// credentials. Repeated requests are rejected with 401 status code, except
+// retries of failed requests, and retries of create requests with
+// WithIdempotency, which get the response of the first request. Describe
+// requests aren't guarded.
+func WithReplayGuard(store replay.Store, window time.Duration) Option {
+	return func(h *hook) {
+		h.replayStore = store
+		h.replayWindow = window
+	}
+}
+
+// WithResponseEncryption configures this webhook to encrypt the response
+// field of create responses to a public key provided in the "encryption_key"
+// field of the input or of a JSON payload, so that only the final consumer
+// can read issued credentials. If required is true, create requests without
+// a key are rejected, otherwise their responses aren't encrypted.
+func WithResponseEncryption(required bool) Option {
+	return func(h *hook) {
+		h.encryption = encryptionOptional
+		if required {
+			h.encryption = encryptionRequired
+		}
+	}
+}
+
+// WithPayloadKeys configures this webhook to decrypt producer payloads
+// encrypted with payload.Encrypt, using any of the provided AES-256 keys.
+// Plain payloads are rejected, and payloads returned by rotation are
+// encrypted with the first key.
+func WithPayloadKeys(keys ...[]byte) Option {
+	return func(h *hook) {
+		h.payloadKeys = keys
+	}
+}
+
+// WithRevokeWorkers sets the number of IDs revoked at the same time, for
+// producers that implement protocol.IDRevoker. The default is 4.
+func WithRevokeWorkers(n int) Option {
+	return func(h *hook) {
+		h.revokeWorkers = n
+	}
+}
+
+// WithSweeper registers every created credential with the provided sweeper,
+// which revokes it if Akeyless doesn't. Credentials expire after ttl, which
+// should be the longest TTL of the producer item, unless the response
+// implements Expirer. The sweeper must be run separately.
+func WithSweeper(s *sweeper.Sweeper, ttl time.Duration) Option {
+	return func(h *hook) {
+		h.sweeper = s
+		h.sweeperTTL = ttl
+	}
+}
@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+)
+
+var errPlainPayload = payload.ErrInvalidPayload.WithMessage("payload must be encrypted")
+
+// decryptPayload replaces an encrypted payload with its plain content. If
+// payload keys are configured, plain payloads are rejected, so that a payload
+// stored in Akeyless is useless without the keys.
+func (h *hook) decryptPayload(p *string) error {
+	if len(h.payloadKeys) == 0 || *p == "" {
+		return nil
+	}
+
+	if !payload.IsEncrypted(*p) {
+		return errPlainPayload
+	}
+
+	plain, err := payload.Decrypt(h.payloadKeys, *p)
+	if err != nil {
+		return payload.ErrInvalidPayload.WithMessage("can't decrypt payload").Wrap(err)
+	}
+
+	*p = string(plain)
+
+	return nil
+}
+
+// encryptPayload encrypts a rotated payload with the first key, so that
+// Akeyless only stores encrypted payloads.
+func (h *hook) encryptPayload(p *string) error {
+	if len(h.payloadKeys) == 0 || *p == "" {
+		return nil
+	}
+
+	enc, err := payload.Encrypt(h.payloadKeys[0], []byte(*p))
+	if err != nil {
+		return err
+	}
+
+	*p = enc
+
+	return nil
+}
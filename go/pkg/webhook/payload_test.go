@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+// payloadKeys returns the current payload key, followed by an old one.
+func payloadKeys(t *testing.T) (current []byte, old []byte) {
+	t.Helper()
+
+	current, old = make([]byte, payload.KeySize), make([]byte, payload.KeySize)
+
+	for _, key := range [][]byte{current, old} {
+		if _, err := rand.Read(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return current, old
+}
+
+func sealPayload(t *testing.T, key []byte, plain string) string {
+	t.Helper()
+
+	enc, err := payload.Encrypt(key, []byte(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return enc
+}
+
+func TestCreateDecryptsPayload(t *testing.T) {
+	current, old := payloadKeys(t)
+
+	// payloads stored before the key was rotated are still accepted
+	for name, key := range map[string][]byte{"current key": current, "old key": old} {
+		t.Run(name, func(t *testing.T) {
+			p := &testProducer{}
+			h := newTestHandler(t, p, WithPayloadKeys(current, old))
+
+			body, _ := json.Marshal(map[string]string{"payload": sealPayload(t, key, `{"password":"s3cr3t"}`)})
+
+			if rec := serve(h, http.MethodPost, "/sync/create", string(body), nil); rec.Code != http.StatusOK {
+				t.Fatalf("create returned %d: %s", rec.Code, rec.Body)
+			}
+
+			if got := p.creates[0].Payload; got != `{"password":"s3cr3t"}` {
+				t.Errorf("producer got payload %q, want the decrypted one", got)
+			}
+		})
+	}
+}
+
+func TestCreateRejectsPayload(t *testing.T) {
+	current, _ := payloadKeys(t)
+	other, _ := payloadKeys(t)
+
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealPayload(t, current, "p"), payload.Prefix))
+	sealed[len(sealed)-1] ^= 1
+	tampered := payload.Prefix + base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{"plain", "p"},
+		{"tampered", tampered},
+		{"unknown key", sealPayload(t, other, "p")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &testProducer{}
+			h := newTestHandler(t, p, WithPayloadKeys(current))
+
+			body, _ := json.Marshal(map[string]string{"payload": tt.payload})
+
+			rec := serve(h, http.MethodPost, "/sync/create", string(body), nil)
+
+			var res protocol.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusBadRequest || res.Error.Code != payload.ErrInvalidPayload.Code {
+				t.Errorf("create returned %d %s, want %s", rec.Code, rec.Body, payload.ErrInvalidPayload.Code)
+			}
+
+			if p.createCount() != 0 {
+				t.Error("producer was called with a payload that isn't encrypted with a known key")
+			}
+		})
+	}
+}
+
+func TestRotateEncryptsPayload(t *testing.T) {
+	current, old := payloadKeys(t)
+	h := newTestHandler(t, &testProducer{}, WithPayloadKeys(current, old))
+
+	body, _ := json.Marshal(map[string]string{"payload": sealPayload(t, old, "admin")})
+
+	rec := serve(h, http.MethodPost, "/sync/rotate", string(body), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rotate returned %d: %s", rec.Code, rec.Body)
+	}
+
+	var res protocol.RotateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if !payload.IsEncrypted(res.Payload) || strings.Contains(res.Payload, "admin") {
+		t.Fatalf("rotate returned payload %q, want an encrypted one", res.Payload)
+	}
+
+	// the rotated payload is re-encrypted with the current key only
+	if _, err := payload.Decrypt([][]byte{old}, res.Payload); err == nil {
+		t.Error("rotated payload can be decrypted with the old key")
+	}
+
+	got, err := payload.Decrypt([][]byte{current}, res.Payload)
+	if err != nil || string(got) != "admin-1" {
+		t.Errorf("rotated payload is %q, %v, want admin-1", got, err)
+	}
+}
+
+func TestRotateRejectsPlainPayload(t *testing.T) {
+	current, _ := payloadKeys(t)
+	h := newTestHandler(t, &testProducer{}, WithPayloadKeys(current))
+
+	rec := serve(h, http.MethodPost, "/sync/rotate", `{"payload":"admin"}`, nil)
+
+	var res protocol.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || rec.Code != http.StatusBadRequest || res.Error.Code != payload.ErrInvalidPayload.Code {
+		t.Errorf("rotate returned %d %s, want %s", rec.Code, rec.Body, payload.ErrInvalidPayload.Code)
+	}
+}
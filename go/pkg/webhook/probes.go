@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/version"
+)
+
+const readinessCheckTimeout = 5 * time.Second
+
+type readinessCheck func(context.Context) error
+
+// readinessReport is returned by /readyz endpoint.
+type readinessReport struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// healthz reports that the process is alive. It doesn't check any
+// dependencies, so that a temporary outage doesn't cause restarts.
+func (h *hook) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyz runs every readiness check concurrently and reports whether the
+// producer is ready to serve requests.
+func (h *hook) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+	defer cancel()
+
+	report := readinessReport{Ready: true, Checks: make(map[string]string, len(h.checks))}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for name, check := range h.checks {
+		wg.Add(1)
+
+		go func(name string, check readinessCheck) {
+			defer wg.Done()
+
+			// probes aren't authenticated, so failure details are only logged
+			status := "ok"
+			if err := check(ctx); err != nil {
+				logging.FromContext(ctx).Warn("readiness check failed", "check", name, "error", err)
+				status = "failed"
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			report.Checks[name] = status
+			if status != "ok" {
+				report.Ready = false
+			}
+		}(name, check)
+	}
+
+	wg.Wait()
+
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, r, status, report)
+}
+
+func (h *hook) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, version.Get())
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.FromContext(r.Context()).Error("failed to write response", "error", err)
+	}
+}
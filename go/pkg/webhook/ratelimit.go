@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/ratelimit"
+)
+
+// concurrencyRetryAfter is sent to callers rejected because too many create
+// operations are already running. It is roughly the time an ACME order takes.
+const concurrencyRetryAfter = 30 * time.Second
+
+var errRateLimited = &protocol.Error{
+	Code:      protocol.CodeRateLimited,
+	Status:    http.StatusTooManyRequests,
+	Message:   "too many requests",
+	Retryable: true,
+}
+
+// KeyFunc extracts rate limit keys from a create request. Every returned key
+// must be allowed by the limiter for the request to proceed. A rule is
+// skipped if no keys are returned.
+type KeyFunc func(r *protocol.CreateRequest) []string
+
+// ByAccessID limits requests per access ID of the client that requested the
+// dynamic secret.
+func ByAccessID() KeyFunc {
+	return func(r *protocol.CreateRequest) []string {
+		if r.ClientInfo.AccessID == "" {
+			return nil
+		}
+
+		return []string{r.ClientInfo.AccessID}
+	}
+}
+
+// ByItemName limits all requests made by the producer with the provided
+// name.
+func ByItemName(name string) KeyFunc {
+	return func(*protocol.CreateRequest) []string {
+		if name == "" {
+			return nil
+		}
+
+		return []string{name}
+	}
+}
+
+// BySubClaim limits requests per value of the provided sub-claim of the
+// client, for example, "email".
+func BySubClaim(name string) KeyFunc {
+	return func(r *protocol.CreateRequest) []string {
+		return r.ClientInfo.SubClaims[name]
+	}
+}
+
+// ByInputField limits requests per value of a top-level string field of the
+// request input. Comma separated values are limited individually, so that
+// for example every domain of a certificate is counted.
+func ByInputField(name string) KeyFunc {
+	return func(r *protocol.CreateRequest) []string {
+		var fields map[string]interface{}
+		if err := r.Input.Decode(&fields); err != nil {
+			return nil
+		}
+
+		s, _ := fields[name].(string)
+
+		var keys []string
+
+		for _, v := range strings.Split(s, ",") {
+			if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+				keys = append(keys, v)
+			}
+		}
+
+		return keys
+	}
+}
+
+type rateLimit struct {
+	name    string
+	limiter ratelimit.Limiter
+	keys    KeyFunc
+}
+
+// limit checks the create request against every configured rate limit. If
+// the limiter itself fails, the request is allowed, so that an outage of a
+// shared limiter store doesn't prevent issuing credentials.
+func (h *hook) limit(r *http.Request, cr *protocol.CreateRequest) error {
+	for _, rl := range h.rateLimits {
+		for _, key := range rl.keys(cr) {
+			ok, retryAfter, err := rl.limiter.Allow(r.Context(), fmt.Sprintf("%s:%s", rl.name, key))
+			if err != nil {
+				logging.FromContext(r.Context()).Error("rate limiter failed", "rule", rl.name, "error", err)
+				continue
+			}
+
+			if !ok {
+				rateLimited.WithLabelValues(h.name, rl.name).Inc()
+
+				return errRateLimited.
+					WithMessage("too many requests, limited by %s", rl.name).
+					WithRetryAfter(retryAfter)
+			}
+		}
+	}
+
+	return nil
+}
+
+// acquire takes a slot of concurrent create operations. The returned
+// function releases it.
+func (h *hook) acquire(r *http.Request) (func(), error) {
+	if h.concurrency == nil {
+		return func() {}, nil
+	}
+
+	release, ok, err := h.concurrency.Acquire(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("concurrency limiter failed", "error", err)
+		return func() {}, nil
+	}
+
+	if !ok {
+		rateLimited.WithLabelValues(h.name, "concurrency").Inc()
+
+		return nil, errRateLimited.
+			WithMessage("too many concurrent requests").
+			WithRetryAfter(concurrencyRetryAfter)
+	}
+
+	return release, nil
+}
@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/ratelimit"
+)
+
+// failingLimiter is a Limiter whose store is down.
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string) (bool, time.Duration, error) {
+	return false, 0, errors.New("store is down")
+}
+
+// createAs sends a create request of the provided access ID.
+func createAs(h http.Handler, accessID string) (int, http.Header, *protocol.ErrorResponse) {
+	rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p","client_info":{"access_id":"`+accessID+`"}}`, nil)
+
+	var res protocol.ErrorResponse
+	if rec.Code != http.StatusOK {
+		_ = json.Unmarshal(rec.Body.Bytes(), &res)
+	}
+
+	return rec.Code, rec.Header(), &res
+}
+
+func TestRateLimit(t *testing.T) {
+	p := &testProducer{}
+	h := newTestHandler(t, p, WithRateLimit("access_id", ratelimit.NewMemory(ratelimit.Rate{Count: 1, Period: time.Hour}), ByAccessID()))
+
+	if code, _, _ := createAs(h, "p-user"); code != http.StatusOK {
+		t.Fatalf("first create returned %d", code)
+	}
+
+	code, header, res := createAs(h, "p-user")
+	if code != http.StatusTooManyRequests {
+		t.Fatalf("second create returned %d, want 429", code)
+	}
+
+	if got := header.Get("Retry-After"); got != "3600" {
+		t.Errorf("Retry-After is %q, want 3600", got)
+	}
+
+	if res.Error.Code != protocol.CodeRateLimited || !res.Error.Retryable {
+		t.Errorf("error is %+v, want a retryable %s", res.Error, protocol.CodeRateLimited)
+	}
+
+	// callers are limited independently
+	if code, _, _ := createAs(h, "p-other"); code != http.StatusOK {
+		t.Errorf("create of another access id returned %d", code)
+	}
+
+	if n := p.createCount(); n != 2 {
+		t.Errorf("producer was called %d times, want 2", n)
+	}
+}
+
+func TestRateLimitStoreFailure(t *testing.T) {
+	h := newTestHandler(t, &testProducer{}, WithRateLimit("access_id", failingLimiter{}, ByAccessID()))
+
+	// an outage of the limiter doesn't prevent issuing credentials
+	if code, _, _ := createAs(h, "p-user"); code != http.StatusOK {
+		t.Errorf("create returned %d, want 200", code)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	p := &testProducer{}
+	h := newTestHandler(t, p, WithConcurrencyLimit(ratelimit.NewSemaphore(0)))
+
+	code, header, res := createAs(h, "p-user")
+	if code != http.StatusTooManyRequests {
+		t.Fatalf("create returned %d, want 429", code)
+	}
+
+	if got := header.Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After is %q, want 30", got)
+	}
+
+	if res.Error.Code != protocol.CodeRateLimited {
+		t.Errorf("error code is %q, want %s", res.Error.Code, protocol.CodeRateLimited)
+	}
+
+	if p.createCount() != 0 {
+		t.Error("producer was called over the concurrency limit")
+	}
+}
@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+// recoverPanic turns panics of handlers into internal errors, so that a bug
+// in a producer fails a single request instead of the whole server. The
+// response includes the correlation ID, and the stack trace is logged.
+func (h *hook) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			// http.ErrAbortHandler is used to abort a response on purpose
+			if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(v)
+			}
+
+			logging.FromContext(r.Context()).Error("recovered from panic", "panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+			writeError(w, r, protocol.ErrInternal.Wrap(fmt.Errorf("panic: %v", v)))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+// Reloadable is a webhook whose options can be replaced while it serves
+// requests, for example, to apply new access IDs or rate limits from a
+// reloaded config file. In-flight requests complete with the options they
+// started with, and connections aren't dropped.
+type Reloadable struct {
+	p       protocol.Producer
+	current atomic.Pointer[http.Handler]
+}
+
+// NewReloadable creates a new webhook that serves the provided producer.
+func NewReloadable(p protocol.Producer, opts ...Option) (*Reloadable, error) {
+	r := &Reloadable{p: p}
+
+	if err := r.Reload(opts...); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload replaces every option of this webhook with the provided ones.
+// Stateful dependencies, such as limiters and audit loggers, should be reused
+// between calls to keep their state.
+func (r *Reloadable) Reload(opts ...Option) error {
+	h, err := New(r.p, opts...)
+	if err != nil {
+		return err
+	}
+
+	r.current.Store(&h)
+
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Reloadable) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	(*r.current.Load()).ServeHTTP(w, req)
+}
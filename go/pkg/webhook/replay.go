@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/auth"
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+)
+
+const resultReplay = "replay"
+
+// replayClockSkew is added to the lifetime of credentials, in case clocks of
+// this server and Akeyless auth service differ.
+const replayClockSkew = time.Minute
+
+var errReplayed = errUnauthorized.WithMessage("credentials were already used")
+
+type replayedKey struct{}
+
+// guardReplay rejects requests whose credentials were already used for the
+// same request. It runs after authentication, so that invalid credentials
+// aren't remembered. Only requests that succeed are remembered, so that
+// failed ones, for example rate limited ones, can be retried. Requests that
+// don't change anything, such as describe, aren't guarded, and neither is
+// revoke, which Akeyless retries if it times out and which has no effect
+// when repeated.
+func (h *hook) guardReplay(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.replayStore == nil || r.Method == http.MethodGet || strings.HasSuffix(r.URL.Path, "/revoke") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, h.maxBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, r, errBodyTooLarge.Wrap(err))
+			} else {
+				writeError(w, r, errInvalidBody.Wrap(err))
+			}
+
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		creds := r.Header.Get(credsHeader)
+
+		// the key covers the request too, since the same credentials may
+		// be used for several requests that differ
+		sum := sha256.New()
+		for _, part := range [][]byte{[]byte(creds), []byte(r.Method + " " + r.URL.Path), body} {
+			_, _ = sum.Write(part)
+			_, _ = sum.Write([]byte{0})
+		}
+
+		key := hex.EncodeToString(sum.Sum(nil))
+
+		ok, err := h.replayStore.Add(r.Context(), key, h.replayTTL(creds))
+		if err != nil {
+			// like limiters, a failing store doesn't prevent issuing
+			// credentials
+			logging.FromContext(r.Context()).Error("replay store failed", "error", err)
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if !ok {
+			// a retried create or rotate may only get the response of
+			// the first request from the idempotency cache, see
+			// idempotent
+			if h.idempotency != nil && (strings.HasSuffix(r.URL.Path, "/create") || strings.HasSuffix(r.URL.Path, "/rotate")) {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), replayedKey{}, true)))
+				return
+			}
+
+			authResults.WithLabelValues(h.name, resultReplay).Inc()
+			writeError(w, r, errReplayed)
+
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status < 200 || rec.status > 299 {
+			// the request may outlive its context, which must not
+			// prevent a retry
+			if err := h.replayStore.Remove(context.WithoutCancel(r.Context()), key); err != nil {
+				logging.FromContext(r.Context()).Error("replay store failed", "error", err)
+			}
+		}
+	})
+}
+
+// replayTTL returns how long credentials must be remembered: until they
+// expire, if their expiration time is known, and for the configured window
+// otherwise.
+func (h *hook) replayTTL(creds string) time.Duration {
+	if exp, ok := auth.Expiry(creds); ok {
+		if ttl := time.Until(exp) + replayClockSkew; ttl > 0 {
+			return ttl
+		}
+	}
+
+	return h.replayWindow
+}
+
+// replayed reports whether guardReplay saw the request before.
+func replayed(ctx context.Context) bool {
+	v, _ := ctx.Value(replayedKey{}).(bool)
+	return v
+}
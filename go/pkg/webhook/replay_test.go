@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/idempotency"
+	"github.com/akeylesslabs/custom-producer/go/pkg/replay"
+)
+
+const createBody = `{"payload":"p","client_info":{"access_id":"p-user"}}`
+
+// denyFirst rejects the first request of every key.
+type denyFirst struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (l *denyFirst) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.seen == nil {
+		l.seen = make(map[string]bool)
+	}
+
+	if !l.seen[key] {
+		l.seen[key] = true
+		return false, time.Second, nil
+	}
+
+	return true, 0, nil
+}
+
+func TestReplayRejected(t *testing.T) {
+	p := &testProducer{}
+	h := newTestHandler(t, p, WithReplayGuard(replay.NewMemory(), time.Minute))
+
+	if rec := serve(h, http.MethodPost, "/sync/create", createBody, nil); rec.Code != http.StatusOK {
+		t.Fatalf("create returned %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(h, http.MethodPost, "/sync/create", createBody, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("replayed create returned %d, want 401", rec.Code)
+	}
+
+	if n := p.createCount(); n != 1 {
+		t.Errorf("producer created %d credentials, want 1", n)
+	}
+}
+
+func TestReplayGuardAllowsRetryAfterRateLimit(t *testing.T) {
+	p := &testProducer{}
+	h := newTestHandler(t, p,
+		WithReplayGuard(replay.NewMemory(), time.Minute),
+		WithRateLimit("access_id", &denyFirst{}, ByAccessID()),
+	)
+
+	rec := serve(h, http.MethodPost, "/sync/create", createBody, nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("create returned %d, want 429", rec.Code)
+	}
+
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("rate limited create has no Retry-After")
+	}
+
+	// the caller retries as told, with the same credentials
+	if rec := serve(h, http.MethodPost, "/sync/create", createBody, nil); rec.Code != http.StatusOK {
+		t.Fatalf("retried create returned %d: %s", rec.Code, rec.Body)
+	}
+
+	if n := p.createCount(); n != 1 {
+		t.Errorf("producer created %d credentials, want 1", n)
+	}
+}
+
+func TestReplayGuardAllowsIdempotentRetry(t *testing.T) {
+	p := &testProducer{}
+	h := newTestHandler(t, p,
+		WithReplayGuard(replay.NewMemory(), time.Minute),
+		WithIdempotency(idempotency.NewMemory(), time.Minute),
+	)
+
+	first := serve(h, http.MethodPost, "/sync/create", createBody, nil)
+	if first.Code != http.StatusOK {
+		t.Fatalf("create returned %d: %s", first.Code, first.Body)
+	}
+
+	// the gateway retries a create whose response it didn't get
+	retry := serve(h, http.MethodPost, "/sync/create", createBody, nil)
+	if retry.Code != http.StatusOK {
+		t.Fatalf("retried create returned %d: %s", retry.Code, retry.Body)
+	}
+
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("retried create returned %s, want the first response %s", retry.Body, first.Body)
+	}
+
+	if n := p.createCount(); n != 1 {
+		t.Errorf("producer created %d credentials, want 1", n)
+	}
+}
+
+// forgetful is an idempotency store that doesn't keep anything, like one
+// whose entries expired.
+type forgetful struct{}
+
+func (forgetful) Get(context.Context, string) ([]byte, bool, error)        { return nil, false, nil }
+func (forgetful) Set(context.Context, string, []byte, time.Duration) error { return nil }
+
+func TestReplayGuardDoesntCreateOnIdempotentReplay(t *testing.T) {
+	p := &testProducer{}
+	h := newTestHandler(t, p,
+		WithReplayGuard(replay.NewMemory(), time.Minute),
+		WithIdempotency(forgetful{}, time.Minute),
+	)
+
+	if rec := serve(h, http.MethodPost, "/sync/create", createBody, nil); rec.Code != http.StatusOK {
+		t.Fatalf("create returned %d: %s", rec.Code, rec.Body)
+	}
+
+	// the first response isn't available, so the replay is rejected
+	if rec := serve(h, http.MethodPost, "/sync/create", createBody, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("replayed create returned %d, want 401", rec.Code)
+	}
+
+	if n := p.createCount(); n != 1 {
+		t.Errorf("producer created %d credentials, want 1", n)
+	}
+}
+
+func TestReplayGuardAllowsRevokeRetry(t *testing.T) {
+	h := newTestHandler(t, &testProducer{}, WithReplayGuard(replay.NewMemory(), time.Minute))
+
+	// the gateway retries a revoke whose response it didn't get
+	for i := 0; i < 2; i++ {
+		if rec := serve(h, http.MethodPost, "/sync/revoke", `{"payload":"p","ids":["id-1"]}`, nil); rec.Code != http.StatusOK {
+			t.Fatalf("revoke #%d returned %d: %s", i+1, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestReplayGuardRotate(t *testing.T) {
+	t.Run("replayed", func(t *testing.T) {
+		p := &testProducer{}
+		h := newTestHandler(t, p, WithReplayGuard(replay.NewMemory(), time.Minute))
+
+		if rec := serve(h, http.MethodPost, "/sync/rotate", `{"payload":"p"}`, nil); rec.Code != http.StatusOK {
+			t.Fatalf("rotate returned %d: %s", rec.Code, rec.Body)
+		}
+
+		if rec := serve(h, http.MethodPost, "/sync/rotate", `{"payload":"p"}`, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("replayed rotate returned %d, want 401", rec.Code)
+		}
+
+		if n := p.rotateCount(); n != 1 {
+			t.Errorf("producer rotated %d times, want once", n)
+		}
+	})
+
+	t.Run("idempotent retry", func(t *testing.T) {
+		p := &testProducer{}
+		h := newTestHandler(t, p,
+			WithReplayGuard(replay.NewMemory(), time.Minute),
+			WithIdempotency(idempotency.NewMemory(), time.Minute),
+		)
+
+		first := serve(h, http.MethodPost, "/sync/rotate", `{"payload":"p"}`, nil)
+		if first.Code != http.StatusOK {
+			t.Fatalf("rotate returned %d: %s", first.Code, first.Body)
+		}
+
+		// the retry gets the new payload, which the first rotation made
+		// the only valid one
+		retry := serve(h, http.MethodPost, "/sync/rotate", `{"payload":"p"}`, nil)
+		if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+			t.Errorf("retried rotate returned %d %s, want the first response %s", retry.Code, retry.Body, first.Body)
+		}
+
+		if n := p.rotateCount(); n != 1 {
+			t.Errorf("producer rotated %d times, want once", n)
+		}
+	})
+}
+
+func TestReplayGuardSkipsDescribe(t *testing.T) {
+	h := newTestHandler(t, &testProducer{}, WithReplayGuard(replay.NewMemory(), time.Minute))
+
+	for i := 0; i < 2; i++ {
+		if rec := serve(h, http.MethodGet, "/sync/describe", "", nil); rec.Code != http.StatusOK {
+			t.Fatalf("describe #%d returned %d: %s", i+1, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestReplayTTL(t *testing.T) {
+	h := &hook{replayWindow: time.Hour}
+
+	jwt := func(claims string) string {
+		return "e30." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+	}
+
+	exp := time.Now().Add(10 * time.Minute).Unix()
+
+	tests := []struct {
+		name  string
+		creds string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"opaque", "opaque-creds", time.Hour, time.Hour},
+		{"jwt", jwt(fmt.Sprintf(`{"exp":%d}`, exp)), 10 * time.Minute, 11 * time.Minute},
+		{"jwt without exp", jwt(`{"sub":"x"}`), time.Hour, time.Hour},
+		{"expired jwt", jwt(`{"exp":1}`), time.Hour, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ttl := h.replayTTL(tt.creds); ttl < tt.min || ttl > tt.max {
+				t.Errorf("replayTTL() = %v, want between %v and %v", ttl, tt.min, tt.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+// defaultRevokeWorkers is the default number of IDs revoked at the same time.
+const defaultRevokeWorkers = 4
+
+var errRevokeFailed = &protocol.Error{
+	Code:      protocol.CodeUpstreamError,
+	Status:    http.StatusBadGateway,
+	Message:   "failed to revoke any of the ids",
+	Retryable: true,
+}
+
+// RevokeResult is the result of revoking a single ID. Failed results are
+// listed in the details of the error returned if no ID was revoked.
+type RevokeResult struct {
+	ID    string `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+// revokeIDs revokes every ID with a bounded number of workers. Only revoked
+// IDs are returned, failures are logged and summarized in the message.
+func (h *hook) revokeIDs(ctx context.Context, p protocol.IDRevoker, r *protocol.RevokeRequest) (*protocol.RevokeResponse, error) {
+	results := make([]RevokeResult, len(r.IDs))
+	ids := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < min(max(h.revokeWorkers, 1), len(r.IDs)); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range ids {
+				results[i].ID = r.IDs[i]
+
+				if err := p.RevokeID(ctx, r.Payload, r.IDs[i]); err != nil {
+					logging.FromContext(ctx).Error("failed to revoke id", "id", r.IDs[i], "error", err)
+					results[i].Error = publicMessage(err)
+				}
+			}
+		}()
+	}
+
+	for i := range r.IDs {
+		ids <- i
+	}
+
+	close(ids)
+	wg.Wait()
+
+	out := &protocol.RevokeResponse{Revoked: []string{}}
+
+	var failed []string
+
+	for _, res := range results {
+		if res.Error == "" {
+			out.Revoked = append(out.Revoked, res.ID)
+		} else {
+			failed = append(failed, fmt.Sprintf("%s: %s", res.ID, res.Error))
+		}
+	}
+
+	if len(failed) == 0 {
+		return out, nil
+	}
+
+	if len(out.Revoked) == 0 {
+		return nil, errRevokeFailed.WithDetails(results)
+	}
+
+	out.Message = fmt.Sprintf("failed to revoke %d of %d ids: %s", len(failed), len(r.IDs), strings.Join(failed, "; "))
+
+	return out, nil
+}
+
+// publicMessage returns the message of an error that may be sent to the
+// caller. Internal details are only logged.
+func publicMessage(err error) string {
+	var pErr *protocol.Error
+	if !errors.As(err, &pErr) {
+		pErr = protocol.ErrInternal
+	}
+
+	return pErr.Message
+}
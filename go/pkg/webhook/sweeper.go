@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/audit"
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/sweeper"
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+)
+
+const opSweep = "sweep"
+
+// Expirer is implemented by producer responses that know when their
+// credential expires, for example, a certificate. Other credentials expire
+// after the TTL set with WithSweeper.
+type Expirer interface {
+	Expires() time.Time
+}
+
+// track registers a created credential with the sweeper. Failing to register
+// it doesn't fail the request, since the credential was already created.
+func (h *hook) track(ctx context.Context, payload string, out *protocol.CreateResponse) {
+	if h.sweeper == nil || out == nil || out.ID == "" {
+		return
+	}
+
+	expires := time.Now().Add(h.sweeperTTL)
+	if e, ok := out.Response.(Expirer); ok {
+		expires = e.Expires()
+	}
+
+	err := h.sweeper.Register(ctx, sweeper.Entry{
+		Producer: h.name,
+		ID:       out.ID,
+		Payload:  payload,
+		Expires:  expires,
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to register credential with sweeper", "id", out.ID, "error", err)
+	}
+}
+
+// untrack forgets revoked credentials.
+func (h *hook) untrack(ctx context.Context, out *protocol.RevokeResponse) {
+	if h.sweeper == nil || out == nil {
+		return
+	}
+
+	if err := h.sweeper.Revoked(ctx, h.name, out.Revoked...); err != nil {
+		logging.FromContext(ctx).Error("failed to remove revoked credentials from sweeper", "error", err)
+	}
+}
+
+// sweepRevoke revokes an orphaned credential the same way Akeyless would,
+// and records it in the audit log.
+func (h *hook) sweepRevoke(p protocol.Producer) sweeper.RevokeFunc {
+	return func(ctx context.Context, e sweeper.Entry) (err error) {
+		ctx, span := tracer.Start(ctx, "sweeper.Revoke")
+		defer func() { tracing.End(span, err) }()
+
+		defer func() {
+			if h.auditLogger == nil {
+				return
+			}
+
+			rec := &audit.Record{Operation: opSweep, ItemName: h.itemName, IDs: []string{e.ID}, Outcome: audit.OutcomeSuccess}
+			if err != nil {
+				rec.Outcome = audit.OutcomeFailure
+			}
+
+			if err := h.auditLogger.Log(ctx, rec); err != nil {
+				logging.FromContext(ctx).Error("failed to write audit record", "error", err)
+			}
+		}()
+
+		payload := e.Payload
+		if err := h.decryptPayload(&payload); err != nil {
+			return err
+		}
+
+		if ir, ok := p.(protocol.IDRevoker); ok {
+			return ir.RevokeID(ctx, payload, e.ID)
+		}
+
+		out, err := p.Revoke(ctx, &protocol.RevokeRequest{Payload: payload, IDs: []string{e.ID}})
+		if err != nil {
+			return err
+		}
+
+		if out == nil || !slices.Contains(out.Revoked, e.ID) {
+			return fmt.Errorf("producer didn't revoke %s", e.ID)
+		}
+
+		return nil
+	}
+}
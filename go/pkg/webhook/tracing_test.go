@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupTracing sets tracing up once per process, since tracers of every
+// package delegate to the first provider set globally.
+var setupTracing = sync.OnceValues(func() (*tracetest.InMemoryExporter, error) {
+	exp := tracetest.NewInMemoryExporter()
+
+	if _, err := tracing.Setup(context.Background(), tracing.WithSpanExporter(exp)); err != nil {
+		return nil, err
+	}
+
+	return exp, nil
+})
+
+func TestCreateSpans(t *testing.T) {
+	exp, err := setupTracing()
+	if err != nil {
+		t.Fatalf("tracing.Setup() failed: %v", err)
+	}
+
+	exp.Reset()
+
+	h := newTestHandler(t, &testProducer{})
+
+	// the caller's trace context is propagated to the server span
+	const (
+		traceID      = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentSpanID = "00f067aa0ba902b7"
+	)
+
+	rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p"}`, http.Header{
+		"Traceparent": {"00-" + traceID + "-" + parentSpanID + "-01"},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create returned %d: %s", rec.Code, rec.Body)
+	}
+
+	// spans are exported in batches
+	if err := otel.GetTracerProvider().(*sdktrace.TracerProvider).ForceFlush(context.Background()); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, s := range exp.GetSpans() {
+		spans[s.Name] = s
+	}
+
+	root, ok := spans["POST /sync/create"]
+	if !ok {
+		t.Fatalf("server span not found in %v", names(spans))
+	}
+
+	if root.SpanKind != trace.SpanKindServer {
+		t.Errorf("server span kind is %v", root.SpanKind)
+	}
+
+	if got := root.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("server span trace id is %s, want the caller's %s", got, traceID)
+	}
+
+	if got := root.Parent.SpanID().String(); got != parentSpanID {
+		t.Errorf("server span parent is %s, want the caller's %s", got, parentSpanID)
+	}
+
+	for _, name := range []string{"hook.auth", "producer.Create"} {
+		s, ok := spans[name]
+		if !ok {
+			t.Errorf("span %s not found in %v", name, names(spans))
+			continue
+		}
+
+		if s.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("span %s isn't a child of the server span", name)
+		}
+	}
+}
+
+func names(spans map[string]tracetest.SpanStub) []string {
+	out := make([]string, 0, len(spans))
+	for name := range spans {
+		out = append(out, name)
+	}
+
+	return out
+}
@@ -0,0 +1,355 @@
+// Package webhook wraps custom producers with HTTP API. It exposes
+// `/sync/create` and `/sync/revoke` endpoints, and `/sync/rotate` for
+// producers that support rotation. These endpoints implement Akeyless Custom
+// Producer protocol.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/audit"
+	"github.com/akeylesslabs/custom-producer/go/pkg/auth"
+	"github.com/akeylesslabs/custom-producer/go/pkg/idempotency"
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"github.com/akeylesslabs/custom-producer/go/pkg/ratelimit"
+	"github.com/akeylesslabs/custom-producer/go/pkg/replay"
+	"github.com/akeylesslabs/custom-producer/go/pkg/sweeper"
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const credsHeader = "AkeylessCreds"
+
+// authenticate and identify validate Akeyless credentials of requests. Tests
+// replace them, so that they don't depend on Akeyless auth service.
+var (
+	authenticate = auth.Authenticate
+	identify     = auth.Identify
+)
+
+// ReadinessChecker is implemented by producers that depend on external
+// services. Its checks are added to /readyz endpoint.
+type ReadinessChecker interface {
+	// ReadinessChecks returns named checks that must pass for the producer
+	// to be considered ready to serve requests.
+	ReadinessChecks() map[string]func(context.Context) error
+}
+
+// New creates a new handler using the provided configuration. The returned
+// handler can be used to serve Akeyless Custom Producer requests using the
+// provided producer.
+func New(p protocol.Producer, opts ...Option) (http.Handler, error) {
+	if err := checkRules(p); err != nil {
+		return nil, err
+	}
+
+	h := newHook(p, opts...)
+
+	router := mux.NewRouter()
+
+	router.Use(h.correlate, h.trace, h.recoverPanic)
+
+	h.probes(router)
+	h.register(router, "", p)
+
+	return router, nil
+}
+
+func newHook(p protocol.Producer, opts ...Option) *hook {
+	h := &hook{
+		checks:        map[string]readinessCheck{"auth": auth.Ping},
+		maxBodySize:   defaultMaxBodySize,
+		revokeWorkers: defaultRevokeWorkers,
+	}
+
+	if rc, ok := p.(ReadinessChecker); ok {
+		for name, check := range rc.ReadinessChecks() {
+			h.checks[name] = check
+		}
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// probes registers endpoints used by orchestrators and monitoring systems.
+// They don't have Akeyless credentials, so these endpoints must not require
+// authentication.
+func (h *hook) probes(router *mux.Router) {
+	router.HandleFunc("/healthz", h.healthz).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", h.readyz).Methods(http.MethodGet)
+	router.HandleFunc("/version", h.version).Methods(http.MethodGet)
+	router.Handle("/metrics", promhttp.Handler()).Methods(http.MethodGet)
+}
+
+// register adds producer endpoints under prefix + "/sync" of the provided
+// router.
+func (h *hook) register(router *mux.Router, prefix string, p protocol.Producer) {
+	// describe only reports the configuration of this webhook, so that
+	// operators can read the schema without a producer token. It must be
+	// registered before the authenticated routes, which would match it too.
+	router.HandleFunc(prefix+"/sync/describe", h.handle(opDescribe, h.describe(p))).Methods(http.MethodGet)
+
+	api := router.PathPrefix(prefix + "/sync").Subrouter()
+
+	// it is very important to authenticate every other request to prevent
+	// abuse
+	api.Use(h.auth, h.guardReplay)
+
+	// Akeyless custom producer must implement at least 2 endpoints:
+	// create and revoke.
+	api.HandleFunc("/create", h.handle(opCreate, h.create(p))).Methods(http.MethodPost)
+	api.HandleFunc("/revoke", h.handle(opRevoke, h.revoke(p))).Methods(http.MethodPost)
+
+	if rp, ok := p.(protocol.Rotator); ok {
+		api.HandleFunc("/rotate", h.handle(opRotate, h.rotate(rp))).Methods(http.MethodPost)
+	}
+
+	if h.sweeper != nil {
+		h.sweeper.Handle(h.name, h.sweepRevoke(p))
+	}
+}
+
+type hook struct {
+	name        string
+	accessID    string
+	itemName    string
+	checks      map[string]readinessCheck
+	auditLogger *audit.Logger
+	rateLimits  []rateLimit
+	concurrency ratelimit.Concurrency
+	idempotency *idempotency.Cache
+	maxBodySize int64
+	strict      bool
+	encryption  encryptionMode
+	payloadKeys [][]byte
+
+	revokeWorkers int
+
+	sweeper    *sweeper.Sweeper
+	sweeperTTL time.Duration
+
+	replayStore  replay.Store
+	replayWindow time.Duration
+}
+
+func (h *hook) auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// requests routed by item name were authenticated by the mux
+		if r.Context().Value(authenticatedKey{}) == h {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, span := tracer.Start(r.Context(), "hook.auth")
+		creds := r.Header.Get(credsHeader)
+		err := authenticate(ctx, creds, h.accessID, auth.WithAllowedItemName(h.itemName))
+		tracing.End(span, err)
+
+		if err == nil {
+			authResults.WithLabelValues(h.name, resultSuccess).Inc()
+			logging.FromContext(ctx).Info("request authorized", "access_id", h.accessID, "item_name", h.itemName)
+			next.ServeHTTP(w, r)
+		} else {
+			authResults.WithLabelValues(h.name, resultFailure).Inc()
+			writeError(w, r, errUnauthorized.Wrap(err))
+		}
+	})
+}
+
+// correlate assigns a correlation ID to every request and sends it back as a
+// response header.
+func (h *hook) correlate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := newCorrelationID(r)
+		w.Header().Set(correlationIDHeader, id)
+		next.ServeHTTP(w, withCorrelationID(r, id))
+	})
+}
+
+type wrapperFunc func(r *http.Request) (interface{}, error)
+
+func (h *hook) create(p protocol.Producer) wrapperFunc {
+	input := inputType(p)
+
+	return func(r *http.Request) (interface{}, error) {
+		cr, body, err := decode[protocol.CreateRequest](h, r)
+		if err != nil {
+			return nil, err
+		}
+
+		// the sweeper keeps the payload as received, so that it stays
+		// encrypted at rest
+		payload := cr.Payload
+
+		if err := h.decryptPayload(&cr.Payload); err != nil {
+			return nil, err
+		}
+
+		// dry runs are made while the producer is set up, usually without
+		// input, and must reach the producer to check its configuration.
+		// They don't issue credentials, so a key isn't required either.
+		dryRun := cr.ClientInfo.AccessID == protocol.DryRunAccessID
+
+		if err := validateInput(input, cr.Input, dryRun); err != nil {
+			return nil, err
+		}
+
+		var key interface{}
+
+		if h.encryption != encryptionDisabled {
+			if key, err = encryptionKey(cr); err != nil {
+				return nil, err
+			}
+
+			if key == nil && h.encryption == encryptionRequired && !dryRun {
+				return nil, errEncryptionRequired
+			}
+		}
+
+		req := idempotentRequest{op: opCreate, payload: cr.Payload, clientInfo: cr.ClientInfo, input: cr.Input}
+
+		return h.idempotent(r, req, func() (interface{}, error) {
+			if err := h.limit(r, cr); err != nil {
+				return nil, err
+			}
+
+			release, err := h.acquire(r)
+			if err != nil {
+				return nil, err
+			}
+
+			defer release()
+
+			ctx, span := tracer.Start(r.Context(), "producer.Create")
+			out, err := p.Create(ctx, cr)
+			tracing.End(span, err)
+
+			rec := &audit.Record{
+				Operation:   opCreate,
+				AccessID:    cr.ClientInfo.AccessID,
+				SubClaims:   cr.ClientInfo.SubClaims,
+				Fingerprint: audit.Fingerprint(body),
+			}
+
+			if out != nil {
+				rec.IDs = []string{out.ID}
+				rec.CertSerial = serialNumber(out.Response)
+			}
+
+			h.record(r, rec, err)
+
+			if err == nil {
+				h.track(r.Context(), payload, out)
+			}
+
+			if err == nil && key != nil {
+				return encrypt(out, key)
+			}
+
+			return out, err
+		})
+	}
+}
+
+func (h *hook) revoke(p protocol.Producer) wrapperFunc {
+	return func(r *http.Request) (interface{}, error) {
+		rr, body, err := decode[protocol.RevokeRequest](h, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := h.decryptPayload(&rr.Payload); err != nil {
+			return nil, err
+		}
+
+		ctx, span := tracer.Start(r.Context(), "producer.Revoke")
+
+		var out *protocol.RevokeResponse
+
+		if ir, ok := p.(protocol.IDRevoker); ok {
+			out, err = h.revokeIDs(ctx, ir, rr)
+		} else {
+			out, err = p.Revoke(ctx, rr)
+		}
+
+		tracing.End(span, err)
+
+		if err == nil {
+			h.untrack(r.Context(), out)
+		}
+
+		rec := &audit.Record{
+			Operation:   opRevoke,
+			Fingerprint: audit.Fingerprint(body),
+		}
+
+		if out != nil {
+			rec.IDs = out.Revoked
+		}
+
+		h.record(r, rec, err)
+
+		return out, err
+	}
+}
+
+func (h *hook) rotate(p protocol.Rotator) wrapperFunc {
+	return func(r *http.Request) (interface{}, error) {
+		rr, body, err := decode[protocol.RotateRequest](h, r)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := h.decryptPayload(&rr.Payload); err != nil {
+			return nil, err
+		}
+
+		// a retried rotation must get the new payload of the first one,
+		// since the payload it was sent with is no longer valid
+		return h.idempotent(r, idempotentRequest{op: opRotate, payload: rr.Payload}, func() (interface{}, error) {
+			ctx, span := tracer.Start(r.Context(), "producer.Rotate")
+			out, err := p.Rotate(ctx, rr)
+			tracing.End(span, err)
+
+			if err == nil && out != nil {
+				err = h.encryptPayload(&out.Payload)
+			}
+
+			h.record(r, &audit.Record{
+				Operation:   opRotate,
+				Fingerprint: audit.Fingerprint(body),
+			}, err)
+
+			return out, err
+		})
+	}
+}
+
+func (h *hook) handle(op string, f wrapperFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		done := observeRequest(h.name, op)
+
+		out, err := f(r)
+		done(err)
+
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			logging.FromContext(r.Context()).Error("failed to write response", "error", err)
+		}
+	}
+}
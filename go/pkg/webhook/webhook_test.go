@@ -0,0 +1,186 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/auth"
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+const (
+	testAccessID = "p-test"
+	testCreds    = "test-creds"
+)
+
+// stubAuth accepts testCreds of testAccessID instead of calling Akeyless
+// auth service.
+func stubAuth(t *testing.T) {
+	t.Helper()
+
+	orig := authenticate
+	authenticate = func(_ context.Context, creds string, accessID string, _ ...auth.Option) error {
+		if creds != testCreds || accessID != testAccessID {
+			return errors.New("invalid credentials")
+		}
+
+		return nil
+	}
+
+	t.Cleanup(func() { authenticate = orig })
+}
+
+// testProducer issues numbered IDs, and records the requests it served.
+type testProducer struct {
+	mu      sync.Mutex
+	creates []*protocol.CreateRequest
+	rotates int
+
+	// createErr, if set, is returned by Create
+	createErr error
+}
+
+func (p *testProducer) Create(_ context.Context, r *protocol.CreateRequest) (*protocol.CreateResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.creates = append(p.creates, r)
+
+	if p.createErr != nil {
+		return nil, p.createErr
+	}
+
+	id := fmt.Sprintf("id-%d", len(p.creates))
+
+	return &protocol.CreateResponse{ID: id, Response: map[string]string{"user": id}}, nil
+}
+
+func (p *testProducer) Revoke(_ context.Context, r *protocol.RevokeRequest) (*protocol.RevokeResponse, error) {
+	return &protocol.RevokeResponse{Revoked: r.IDs}, nil
+}
+
+func (p *testProducer) Rotate(_ context.Context, r *protocol.RotateRequest) (*protocol.RotateResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rotates++
+
+	return &protocol.RotateResponse{Payload: fmt.Sprintf("%s-%d", r.Payload, p.rotates)}, nil
+}
+
+func (p *testProducer) rotateCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.rotates
+}
+
+func (p *testProducer) createCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.creates)
+}
+
+// newTestHandler creates a webhook of p that accepts testCreds.
+func newTestHandler(t *testing.T, p protocol.Producer, opts ...Option) http.Handler {
+	t.Helper()
+
+	stubAuth(t)
+
+	h, err := New(p, append([]Option{WithAllowedAccessID(testAccessID)}, opts...)...)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	return h
+}
+
+// serve sends a request with testCreds to h. Requests with a body are sent
+// as JSON.
+func serve(h http.Handler, method string, path string, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(credsHeader, testCreds)
+
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for k, v := range header {
+		req.Header[http.CanonicalHeaderKey(k)] = v
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestCreate(t *testing.T) {
+	p := &testProducer{}
+	h := newTestHandler(t, p)
+
+	rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p","client_info":{"access_id":"p-user"}}`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create returned %d: %s", rec.Code, rec.Body)
+	}
+
+	if want := `{"id":"id-1","response":{"user":"id-1"}}`; strings.TrimSpace(rec.Body.String()) != want {
+		t.Errorf("create returned %s, want %s", rec.Body, want)
+	}
+
+	if got := p.creates[0].ClientInfo.AccessID; got != "p-user" {
+		t.Errorf("producer got access id %q, want p-user", got)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	p := &testProducer{}
+	h := newTestHandler(t, p)
+
+	rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"p"}`, http.Header{credsHeader: {"stolen"}})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("create with invalid credentials returned %d, want 401", rec.Code)
+	}
+
+	if p.createCount() != 0 {
+		t.Error("producer was called with invalid credentials")
+	}
+}
+
+func TestErrorLogsRedacted(t *testing.T) {
+	const secret = "s3cr3t-value"
+
+	var buf bytes.Buffer
+
+	orig := slog.Default()
+	slog.SetDefault(logging.New(&buf, slog.LevelDebug))
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	p := &testProducer{createErr: fmt.Errorf("upstream rejected {\"password\":\"%s\"}", secret)}
+	h := newTestHandler(t, p)
+
+	rec := serve(h, http.MethodPost, "/sync/create", `{"payload":"{\"password\":\"`+secret+`\"}"}`, nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("create returned %d, want 500", rec.Code)
+	}
+
+	if !strings.Contains(buf.String(), "request ended with error") {
+		t.Fatalf("error wasn't logged: %s", buf.String())
+	}
+
+	for _, out := range []string{buf.String(), rec.Body.String()} {
+		if strings.Contains(out, secret) {
+			t.Errorf("secret leaked: %s", out)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/config"
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/server"
+)
+
+// Config configures Splunk producer and its server.
+type Config struct {
+	Akeyless Akeyless      `yaml:"akeyless" toml:"akeyless" json:"akeyless"`
+	Splunk   Splunk        `yaml:"splunk" toml:"splunk" json:"splunk"`
+	Server   server.Config `yaml:"server" toml:"server" json:"server"`
+	Sweeper  Sweeper       `yaml:"sweeper" toml:"sweeper" json:"sweeper"`
+	Log      Log           `yaml:"log" toml:"log" json:"log"`
+	Tracing  Tracing       `yaml:"tracing" toml:"tracing" json:"tracing"`
+
+	// PayloadKeys are base64 encoded AES-256 keys that decrypt producer
+	// payloads. If set, plain payloads are rejected.
+	PayloadKeys []string `yaml:"payload_keys" toml:"payload_keys" json:"payload_keys" env:"PAYLOAD_KEYS"`
+
+	// ReplayWindow enables rejection of replayed credentials if set.
+	ReplayWindow config.Duration `yaml:"replay_window" toml:"replay_window" json:"replay_window" env:"REPLAY_WINDOW"`
+}
+
+// Akeyless restricts which producers may call this server.
+type Akeyless struct {
+	AccessID string `yaml:"access_id" toml:"access_id" json:"access_id" env:"AKEYLESS_ACCESS_ID"`
+	ItemName string `yaml:"item_name" toml:"item_name" json:"item_name" env:"AKEYLESS_ITEM_NAME"`
+}
+
+// Splunk configures the producer itself.
+type Splunk struct {
+	AllowedRoles   []string `yaml:"allowed_roles" toml:"allowed_roles" json:"allowed_roles" env:"SPLUNK_ALLOWED_ROLES"`
+	DefaultRoles   []string `yaml:"default_roles" toml:"default_roles" json:"default_roles" env:"SPLUNK_DEFAULT_ROLES"`
+	UsernamePrefix string   `yaml:"username_prefix" toml:"username_prefix" json:"username_prefix" env:"SPLUNK_USERNAME_PREFIX"`
+}
+
+// Sweeper configures deletion of users that Akeyless didn't revoke. It is
+// enabled if TTL is set.
+type Sweeper struct {
+	// TTL is the longest TTL of the producer item.
+	TTL config.Duration `yaml:"ttl" toml:"ttl" json:"ttl" env:"SWEEPER_TTL"`
+
+	server.SweeperConfig `yaml:",inline"`
+}
+
+// Log configures logging.
+type Log struct {
+	Level string `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL"`
+}
+
+// Tracing configures OpenTelemetry tracing.
+type Tracing struct {
+	Exporter string `yaml:"exporter" toml:"exporter" json:"exporter" env:"OTEL_TRACES_EXPORTER"`
+}
+
+// Validate implements config.Validator. Every problem is reported at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Akeyless.AccessID == "" {
+		errs = append(errs, errors.New("akeyless.access_id is required"))
+	}
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, errors.New("server.tls_cert_file and server.tls_key_file must be set together"))
+	}
+
+	if c.Server.ClientCAFile != "" && c.Server.TLSCertFile == "" {
+		errs = append(errs, errors.New("server.client_ca_file requires TLS"))
+	}
+
+	for i, key := range c.PayloadKeys {
+		if _, err := payload.ParseKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("payload_keys[%d]: %w", i, err))
+		}
+	}
+
+	if c.Sweeper.TTL < 0 || c.Sweeper.Interval < 0 || c.Sweeper.Grace < 0 {
+		errs = append(errs, errors.New("sweeper durations must not be negative"))
+	}
+
+	// the store keeps payloads as received, which must not be plain text
+	if c.Sweeper.StoreFile != "" && len(c.PayloadKeys) == 0 {
+		errs = append(errs, errors.New("sweeper.store_file requires payload_keys"))
+	}
+
+	return errors.Join(errs...)
+}
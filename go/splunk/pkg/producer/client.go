@@ -0,0 +1,175 @@
+package producer
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxResponseSize limits the size of Splunk responses we are willing to read.
+const maxResponseSize = 1 << 20
+
+// errUserNotFound is returned when the user to delete doesn't exist.
+var errUserNotFound = errors.New("user not found")
+
+// client calls Splunk REST API as the administrator in the payload.
+type client struct {
+	base     *url.URL
+	username string
+	password string
+	http     *http.Client
+}
+
+// client creates a Splunk client for the provided payload. Clients of
+// payloads with a CA certificate trust only that CA.
+func (p *Producer) client(pl *Payload) (*client, error) {
+	base, err := url.Parse(strings.TrimSuffix(pl.URL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("can't parse splunk url: %w", err)
+	}
+
+	hc := p.httpClient
+
+	if pl.CACert != "" {
+		if hc, err = p.clientWithCA(pl.CACert); err != nil {
+			return nil, err
+		}
+	}
+
+	return &client{base: base, username: pl.Username, password: pl.Password, http: hc}, nil
+}
+
+// clientWithCA returns an HTTP client that trusts only the provided CA
+// certificates. Clients are reused, so that their connections are too.
+func (p *Producer) clientWithCA(caCert string) (*http.Client, error) {
+	key := sha256.Sum256([]byte(caCert))
+
+	if hc, ok := p.caClients.Load(key); ok {
+		return hc.(*http.Client), nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(caCert)) {
+		return nil, errors.New("ca_cert doesn't include any PEM certificate")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if base, ok := p.httpClient.Transport.(*http.Transport); ok {
+		transport = base.Clone()
+	}
+
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+
+	hc := &http.Client{Transport: transport, Timeout: p.httpClient.Timeout}
+	p.caClients.Store(key, hc)
+
+	return hc, nil
+}
+
+// createUser creates a user with the provided roles.
+func (c *client) createUser(ctx context.Context, name string, password string, roles []string) (err error) {
+	ctx, span := tracer.Start(ctx, "splunk.create_user", trace.WithAttributes(
+		attribute.String("username", name),
+		attribute.StringSlice("roles", roles),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	form := url.Values{"name": {name}, "password": {password}, "roles": roles}
+
+	return c.do(ctx, http.MethodPost, "/services/authentication/users", form)
+}
+
+// deleteUser deletes a user. It returns errUserNotFound if the user doesn't
+// exist.
+func (c *client) deleteUser(ctx context.Context, name string) (err error) {
+	ctx, span := tracer.Start(ctx, "splunk.delete_user", trace.WithAttributes(attribute.String("username", name)))
+	defer func() { tracing.End(span, err) }()
+
+	return c.do(ctx, http.MethodDelete, "/services/authentication/users/"+url.PathEscape(name), nil)
+}
+
+// currentContext reads the context of the administrator, to verify that its
+// credentials work.
+func (c *client) currentContext(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "splunk.current_context")
+	defer func() { tracing.End(span, err) }()
+
+	return c.do(ctx, http.MethodGet, "/services/authentication/current-context", nil)
+}
+
+// do sends a request to Splunk, and fails unless it succeeded.
+func (c *client) do(ctx context.Context, method string, path string, form url.Values) error {
+	u := c.base.JoinPath(path)
+	u.RawQuery = url.Values{"output_mode": {"json"}}.Encode()
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return fmt.Errorf("can't create splunk request: %w", err)
+	}
+
+	req.SetBasicAuth(c.username, c.password)
+
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("splunk request failed: %w", err)
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("can't read splunk response body: %w", err)
+	}
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return nil
+	case res.StatusCode == http.StatusNotFound && method == http.MethodDelete:
+		return errUserNotFound
+	default:
+		return fmt.Errorf("unexpected splunk response code %d: %s", res.StatusCode, messages(data))
+	}
+}
+
+// messages returns the error messages of a Splunk response. Splunk reports
+// errors as {"messages": [{"type": "ERROR", "text": "..."}]}.
+func messages(data []byte) string {
+	var res struct {
+		Messages []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"messages"`
+	}
+
+	if err := json.Unmarshal(data, &res); err != nil || len(res.Messages) == 0 {
+		return "no error message"
+	}
+
+	texts := make([]string, 0, len(res.Messages))
+	for _, m := range res.Messages {
+		texts = append(texts, m.Text)
+	}
+
+	return strings.Join(texts, "; ")
+}
@@ -0,0 +1,39 @@
+package producer
+
+import "net/http"
+
+// Option is a single configuration parameter used by this producer.
+type Option func(*Producer)
+
+// WithAllowedRoles sets the roles that users may request in the input. By
+// default, users can't choose roles, and always get the default roles.
+func WithAllowedRoles(roles ...string) Option {
+	return func(p *Producer) {
+		p.allowedRoles = roles
+	}
+}
+
+// WithDefaultRoles sets the roles of users that don't request any. The
+// default is "user".
+func WithDefaultRoles(roles ...string) Option {
+	return func(p *Producer) {
+		p.defaultRoles = roles
+	}
+}
+
+// WithUsernamePrefix sets the prefix of temporary user names. The default is
+// "tmp.". This producer only deletes users whose name has this prefix.
+func WithUsernamePrefix(prefix string) Option {
+	return func(p *Producer) {
+		p.usernamePrefix = prefix
+	}
+}
+
+// WithHTTPClient configures this producer to call Splunk with the provided
+// client. Its transport is replaced for Splunk deployments whose payload
+// includes a CA certificate.
+func WithHTTPClient(c *http.Client) Option {
+	return func(p *Producer) {
+		p.httpClient = c
+	}
+}
@@ -0,0 +1,211 @@
+// Package producer implements temporary Splunk users using Akeyless Dynamic
+// Secrets. Users are created and deleted through Splunk REST API, using the
+// administrator credentials stored in the producer payload.
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/logging"
+	"github.com/akeylesslabs/custom-producer/go/pkg/password"
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	defaultRole           = "user"
+	defaultUsernamePrefix = "tmp."
+	defaultTimeout        = 30 * time.Second
+
+	usernameLength = 12
+	passwordLength = 24
+)
+
+var tracer = otel.Tracer("github.com/akeylesslabs/custom-producer/go/splunk/pkg/producer")
+
+// ErrInvalidInput is returned when the input provided alongside
+// `get-dynamic-secret-value` operation is invalid or requests roles that
+// aren't allowed.
+var ErrInvalidInput = protocol.NewError(protocol.CodeInvalidInput, http.StatusBadRequest, "invalid input")
+
+// ErrSplunkFailed is returned when a Splunk request fails. The underlying
+// error is only logged since it may include internal details.
+var ErrSplunkFailed = &protocol.Error{
+	Code:      protocol.CodeUpstreamError,
+	Status:    http.StatusBadGateway,
+	Message:   "splunk request failed",
+	Retryable: true,
+}
+
+// ErrDryRunFailed is returned when Splunk rejects the credentials in the
+// payload during a dry run.
+var ErrDryRunFailed = protocol.NewError(protocol.CodeUnavailable, http.StatusServiceUnavailable, "dry run failed")
+
+// Producer creates temporary Splunk users.
+type Producer struct {
+	allowedRoles   []string
+	defaultRoles   []string
+	usernamePrefix string
+	httpClient     *http.Client
+
+	// caClients are HTTP clients of payloads with a CA certificate, by the
+	// hash of the certificate
+	caClients sync.Map
+}
+
+// New creates a new Producer with the provided options.
+func New(opts ...Option) (*Producer, error) {
+	p := &Producer{
+		defaultRoles:   []string{defaultRole},
+		usernamePrefix: defaultUsernamePrefix,
+		httpClient:     &http.Client{Timeout: defaultTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if len(p.defaultRoles) == 0 {
+		return nil, errors.New("at least one default role is required")
+	}
+
+	if p.usernamePrefix == "" {
+		return nil, errors.New("username prefix must not be empty, it protects other users from being deleted")
+	}
+
+	return p, nil
+}
+
+// Create creates a new Splunk user with a random name and password.
+func (p *Producer) Create(ctx context.Context, r *protocol.CreateRequest) (*protocol.CreateResponse, error) {
+	pl, err := payload.Decode[Payload](r.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.client(pl)
+	if err != nil {
+		return nil, payload.ErrInvalidPayload.Wrap(err)
+	}
+
+	// dry run mode makes sure that the payload is valid without creating
+	// a user
+	if r.ClientInfo.AccessID == protocol.DryRunAccessID {
+		if err := c.currentContext(ctx); err != nil {
+			return nil, ErrDryRunFailed.Wrap(err)
+		}
+
+		return &protocol.CreateResponse{}, nil
+	}
+
+	var inp Input
+	if err := r.Input.Decode(&inp); err != nil {
+		return nil, ErrInvalidInput.Wrap(err)
+	}
+
+	roles, err := p.roles(inp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Splunk user names are case-insensitive
+	suffix, err := password.Generate(usernameLength, password.Lowercase+password.Digits)
+	if err != nil {
+		return nil, err
+	}
+
+	name := p.usernamePrefix + suffix
+
+	// passwords are alphanumeric to keep them easy to paste
+	pass, err := password.Generate(passwordLength, password.Lowercase, password.Uppercase, password.Digits)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.createUser(ctx, name, pass, roles); err != nil {
+		return nil, ErrSplunkFailed.Wrap(fmt.Errorf("can't create user %s: %w", name, err))
+	}
+
+	return &protocol.CreateResponse{
+		ID:       name,
+		Response: &User{Username: name, Password: pass, Roles: roles},
+	}, nil
+}
+
+// Revoke deletes every user in the request, and reports only the ones that
+// were deleted. Webhooks use RevokeID instead, to delete users concurrently.
+func (p *Producer) Revoke(ctx context.Context, r *protocol.RevokeRequest) (*protocol.RevokeResponse, error) {
+	out := &protocol.RevokeResponse{Revoked: []string{}}
+
+	var failed []string
+
+	for _, id := range r.IDs {
+		if err := p.RevokeID(ctx, r.Payload, id); err != nil {
+			logging.FromContext(ctx).Error("failed to revoke id", "id", id, "error", err)
+			failed = append(failed, id)
+
+			continue
+		}
+
+		out.Revoked = append(out.Revoked, id)
+	}
+
+	if len(failed) > 0 {
+		out.Message = fmt.Sprintf("failed to revoke %d of %d ids: %s", len(failed), len(r.IDs), strings.Join(failed, ", "))
+	}
+
+	return out, nil
+}
+
+// RevokeID deletes a single user. Users that were already deleted are
+// reported as revoked, so that revoke requests can be retried.
+func (p *Producer) RevokeID(ctx context.Context, rawPayload string, id string) error {
+	if !strings.HasPrefix(id, p.usernamePrefix) {
+		return ErrInvalidInput.WithMessage("user '%s' wasn't created by this producer", id)
+	}
+
+	pl, err := payload.Decode[Payload](rawPayload)
+	if err != nil {
+		return err
+	}
+
+	c, err := p.client(pl)
+	if err != nil {
+		return payload.ErrInvalidPayload.Wrap(err)
+	}
+
+	if err := c.deleteUser(ctx, id); err != nil && !errors.Is(err, errUserNotFound) {
+		return ErrSplunkFailed.Wrap(fmt.Errorf("can't delete user %s: %w", id, err))
+	}
+
+	return nil
+}
+
+// Describe implements protocol.Describer.
+func (p *Producer) Describe() protocol.Description {
+	return protocol.Description{Input: Input{}, Payload: Payload{}}
+}
+
+// roles returns the roles of a new user: the requested ones if all of them
+// are allowed, or the default ones.
+func (p *Producer) roles(inp Input) ([]string, error) {
+	if len(inp.Roles) == 0 {
+		return p.defaultRoles, nil
+	}
+
+	for _, role := range inp.Roles {
+		if !slices.Contains(p.allowedRoles, role) {
+			return nil, ErrInvalidInput.WithMessage("role '%s' is not allowed", role)
+		}
+	}
+
+	return inp.Roles, nil
+}
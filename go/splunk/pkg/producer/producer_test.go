@@ -0,0 +1,285 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/protocol"
+)
+
+const (
+	adminUser     = "admin"
+	adminPassword = "changeme"
+)
+
+// fakeSplunk implements the parts of Splunk REST API used by the producer.
+type fakeSplunk struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	users map[string][]string
+}
+
+func newFakeSplunk(t *testing.T) *fakeSplunk {
+	t.Helper()
+
+	f := &fakeSplunk{users: make(map[string][]string)}
+	f.Server = httptest.NewTLSServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeSplunk) serve(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != adminUser || pass != adminPassword {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"messages":[{"type":"WARN","text":"call not properly authenticated"}]}`))
+
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	const users = "/services/authentication/users"
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/services/authentication/current-context":
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost && r.URL.Path == users:
+		name := r.PostFormValue("name")
+		if _, ok := f.users[name]; ok || name == "" || r.PostFormValue("password") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		f.users[name] = r.PostForm["roles"]
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, users+"/"):
+		name := strings.TrimPrefix(r.URL.Path, users+"/")
+		if _, ok := f.users[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"messages":[{"type":"ERROR","text":"User does not exist"}]}`))
+
+			return
+		}
+
+		delete(f.users, name)
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeSplunk) roles(name string) ([]string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	roles, ok := f.users[name]
+
+	return roles, ok
+}
+
+func (f *fakeSplunk) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.users)
+}
+
+// payload returns a payload of the fake, with extra fields merged in.
+func (f *fakeSplunk) payload(t *testing.T, extra map[string]string) string {
+	t.Helper()
+
+	fields := map[string]string{"url": f.URL, "username": adminUser, "password": adminPassword}
+	for k, v := range extra {
+		fields[k] = v
+	}
+
+	bs, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(bs)
+}
+
+func newTestProducer(t *testing.T, f *fakeSplunk, opts ...Option) *Producer {
+	t.Helper()
+
+	p, err := New(append([]Option{WithHTTPClient(f.Client())}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestCreate(t *testing.T) {
+	f := newFakeSplunk(t)
+	p := newTestProducer(t, f)
+
+	out, err := p.Create(context.Background(), &protocol.CreateRequest{Payload: f.payload(t, nil)})
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	user := out.Response.(*User)
+
+	if out.ID != user.Username || !strings.HasPrefix(user.Username, defaultUsernamePrefix) {
+		t.Errorf("created user %q with id %q", user.Username, out.ID)
+	}
+
+	if len(user.Password) != passwordLength {
+		t.Errorf("password has %d characters, want %d", len(user.Password), passwordLength)
+	}
+
+	roles, ok := f.roles(user.Username)
+	if !ok {
+		t.Fatalf("user %s wasn't created in splunk", user.Username)
+	}
+
+	if !slices.Equal(roles, []string{defaultRole}) {
+		t.Errorf("user has roles %v, want the default ones", roles)
+	}
+}
+
+func TestCreateWithEncryptionKeyInPayload(t *testing.T) {
+	f := newFakeSplunk(t)
+	p := newTestProducer(t, f)
+
+	// the webhook reads the key from the payload, so the producer must
+	// accept it
+	pl := f.payload(t, map[string]string{"encryption_key": "-----BEGIN PUBLIC KEY-----"})
+
+	if _, err := p.Create(context.Background(), &protocol.CreateRequest{Payload: pl}); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+}
+
+func TestCreateRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{"default", `{}`, []string{"viewer"}, false},
+		{"allowed", `{"roles":["power","user"]}`, []string{"power", "user"}, false},
+		{"not allowed", `{"roles":["power","admin"]}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeSplunk(t)
+			p := newTestProducer(t, f, WithAllowedRoles("power", "user"), WithDefaultRoles("viewer"))
+
+			out, err := p.Create(context.Background(), &protocol.CreateRequest{
+				Payload: f.payload(t, nil),
+				Input:   protocol.Input(tt.input),
+			})
+
+			if tt.wantErr {
+				var pErr *protocol.Error
+				if !errors.As(err, &pErr) || pErr.Code != protocol.CodeInvalidInput {
+					t.Fatalf("Create() = %v, want invalid input", err)
+				}
+
+				if n := f.count(); n != 0 {
+					t.Errorf("%d users were created", n)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Create() failed: %v", err)
+			}
+
+			if roles, _ := f.roles(out.ID); !slices.Equal(roles, tt.want) {
+				t.Errorf("user has roles %v, want %v", roles, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	f := newFakeSplunk(t)
+	p := newTestProducer(t, f)
+	pl := f.payload(t, nil)
+
+	out, err := p.Create(context.Background(), &protocol.CreateRequest{Payload: pl})
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	// the second id was already deleted, which counts as revoked
+	ids := []string{out.ID, defaultUsernamePrefix + "deleted"}
+
+	res, err := p.Revoke(context.Background(), &protocol.RevokeRequest{Payload: pl, IDs: ids})
+	if err != nil {
+		t.Fatalf("Revoke() failed: %v", err)
+	}
+
+	if !slices.Equal(res.Revoked, ids) || res.Message != "" {
+		t.Errorf("Revoke() = %+v, want every id revoked", res)
+	}
+
+	if _, ok := f.roles(out.ID); ok {
+		t.Error("user wasn't deleted in splunk")
+	}
+}
+
+func TestRevokeIDFailures(t *testing.T) {
+	f := newFakeSplunk(t)
+	p := newTestProducer(t, f)
+
+	tests := []struct {
+		name    string
+		payload string
+		id      string
+		code    string
+	}{
+		{"user of another producer", f.payload(t, nil), "admin", protocol.CodeInvalidInput},
+		{"invalid admin credentials", f.payload(t, map[string]string{"password": "wrong"}), defaultUsernamePrefix + "x", protocol.CodeUpstreamError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.RevokeID(context.Background(), tt.payload, tt.id)
+
+			var pErr *protocol.Error
+			if !errors.As(err, &pErr) || pErr.Code != tt.code {
+				t.Errorf("RevokeID() = %v, want %s", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	f := newFakeSplunk(t)
+	p := newTestProducer(t, f)
+
+	dryRun := protocol.ClientInfo{AccessID: protocol.DryRunAccessID}
+
+	if _, err := p.Create(context.Background(), &protocol.CreateRequest{Payload: f.payload(t, nil), ClientInfo: dryRun}); err != nil {
+		t.Errorf("dry run failed: %v", err)
+	}
+
+	if n := f.count(); n != 0 {
+		t.Errorf("dry run created %d users", n)
+	}
+
+	pl := f.payload(t, map[string]string{"password": "wrong"})
+
+	if _, err := p.Create(context.Background(), &protocol.CreateRequest{Payload: pl, ClientInfo: dryRun}); !errors.Is(err, ErrDryRunFailed) {
+		t.Errorf("dry run with invalid credentials = %v, want %v", err, ErrDryRunFailed)
+	}
+}
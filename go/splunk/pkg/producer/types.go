@@ -0,0 +1,26 @@
+package producer
+
+// Payload is the producer payload configured in Akeyless. It holds the
+// credentials of a Splunk administrator allowed to create and delete users,
+// so it should be [encrypted].
+//
+// [encrypted]: https://github.com/akeylesslabs/custom-producer/tree/master/go#payloads
+type Payload struct {
+	URL      string `json:"url" validate:"required" pattern:"https://[^/?#]+(/[^?#]*)?" description:"Splunk management URL, for example, https://splunk.example.com:8089"`
+	Username string `json:"username" validate:"required" description:"Splunk administrator allowed to create and delete users"`
+	Password string `json:"password" validate:"required" description:"Password of the administrator"`
+	CACert   string `json:"ca_cert,omitempty" description:"PEM encoded CA certificates that issued the Splunk certificate, if it isn't trusted by the system"`
+}
+
+// Input includes variables specific to Splunk producer. The input should be
+// provided with `get-dynamic-secret-value` operation.
+type Input struct {
+	Roles []string `json:"roles,omitempty" validate:"max=20" pattern:"[A-Za-z0-9_.-]+" description:"Splunk roles of the temporary user. Must be allowed by the producer, the default roles are used if empty"`
+}
+
+// User is the temporary Splunk user returned by create operation.
+type User struct {
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	Roles    []string `json:"roles"`
+}
@@ -0,0 +1,61 @@
+// Command payload generates payload keys and encrypts producer payloads
+// before they are stored in Akeyless.
+//
+//	payload keygen
+//	PAYLOAD_KEY=... payload encrypt < payload.json
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/akeylesslabs/custom-producer/go/pkg/payload"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "keygen":
+		key := make([]byte, payload.KeySize)
+		if _, err := rand.Read(key); err != nil {
+			fatal(err)
+		}
+
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+	case "encrypt":
+		key, err := payload.ParseKey(os.Getenv("PAYLOAD_KEY"))
+		if err != nil {
+			fatal(err)
+		}
+
+		plain, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fatal(err)
+		}
+
+		enc, err := payload.Encrypt(key, plain)
+		if err != nil {
+			fatal(err)
+		}
+
+		fmt.Println(enc)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: payload keygen | PAYLOAD_KEY=<key> payload encrypt < payload.json")
+	os.Exit(2)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}